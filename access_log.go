@@ -0,0 +1,94 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/netip"
+	"sync/atomic"
+	"time"
+)
+
+// AccessLogSampler decides whether a completed request should be logged.
+type AccessLogSampler func(r *http.Request, status int) bool
+
+type accessLogOption func(*accessLogConfig)
+
+// accessLogConfig holds the configuration of the access log middleware.
+type accessLogConfig struct {
+	sampler        AccessLogSampler
+	clientIP       bool
+	trustedProxies []netip.Prefix
+}
+
+// WithAccessLogSampler sets the sampler deciding which requests are logged, e.g. SampleOneIn.
+// By default every request is logged.
+func WithAccessLogSampler(sampler AccessLogSampler) accessLogOption {
+	return func(cfg *accessLogConfig) {
+		cfg.sampler = sampler
+	}
+}
+
+// WithAccessLogClientIP adds the IP address of the client, resolved with ClientIP, to the log entries as "client_ip".
+// Behind a reverse proxy, r.RemoteAddr is the address of the proxy; the forwarding headers are used
+// only if it belongs to one of the trusted proxy prefixes.
+func WithAccessLogClientIP(trustedProxies ...netip.Prefix) accessLogOption {
+	return func(cfg *accessLogConfig) {
+		cfg.clientIP = true
+		cfg.trustedProxies = trustedProxies
+	}
+}
+
+// SampleOneIn returns a sampler which logs one in n successful (2xx) requests
+// and always logs the others, so that errors stay visible while the log volume is cut.
+// If n is less than 2, every request is logged.
+func SampleOneIn(n int) AccessLogSampler {
+	var counter atomic.Uint64
+	return func(r *http.Request, status int) bool {
+		if n < 2 || status < 200 || status >= 300 {
+			return true
+		}
+		return counter.Add(1)%uint64(n) == 1
+	}
+}
+
+// LoggingMiddleware logs every completed request with the given logger:
+// the method, path, status, response size, duration and remote address.
+// Requests with 5xx responses are logged as errors, the rest as info.
+func LoggingMiddleware(log Logger, opts ...accessLogOption) func(http.Handler) http.Handler {
+	cfg := &accessLogConfig{}
+	for _, o := range opts {
+		o(cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := newResponseWriter(w)
+
+			defer func() {
+				status := rw.Status()
+				if cfg.sampler != nil && !cfg.sampler(r, status) {
+					return
+				}
+
+				logFn := log.InfoContext
+				if status >= http.StatusInternalServerError {
+					logFn = log.ErrorContext
+				}
+				fields := []interface{}{
+					"method", r.Method,
+					"path", r.URL.Path,
+					"status", status,
+					"bytes", rw.Written(),
+					"duration", time.Since(start),
+					"remote_addr", r.RemoteAddr,
+				}
+				if cfg.clientIP {
+					fields = append(fields, "client_ip", ClientIP(r, cfg.trustedProxies).String())
+				}
+				logFn(r.Context(), "http request", fields...)
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
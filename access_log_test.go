@@ -0,0 +1,68 @@
+package httpserver_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggingMiddlewareSampling(t *testing.T) {
+	log := &httpserver.MemoryLogger{}
+	handler := httpserver.LoggingMiddleware(log,
+		httpserver.WithAccessLogSampler(httpserver.SampleOneIn(10)),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i := 0; i < 100; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	for i := 0; i < 5; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/error", nil))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+	}
+
+	counts := make(map[int]int)
+	for _, e := range log.Entries() {
+		if status, ok := e.Field("status"); ok {
+			counts[status.(int)]++
+		}
+	}
+	require.Equal(t, 10, counts[http.StatusOK], "successes must be sampled")
+	require.Equal(t, 5, counts[http.StatusInternalServerError], "errors must always be logged")
+	require.Equal(t, 5, counts[http.StatusNotFound], "errors must always be logged")
+}
+
+func TestLoggingMiddlewareClientIP(t *testing.T) {
+	log := &httpserver.MemoryLogger{}
+	handler := httpserver.LoggingMiddleware(log,
+		httpserver.WithAccessLogClientIP(netip.MustParsePrefix("10.0.0.0/8")),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, remoteAddr := range []string{"10.0.0.1:1234", "203.0.113.7:1234"} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = remoteAddr
+		r.Header.Set("X-Forwarded-For", "198.51.100.1")
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	entries := log.Entries()
+	require.Len(t, entries, 2)
+	clientIP, ok := entries[0].Field("client_ip")
+	require.True(t, ok)
+	require.Equal(t, "198.51.100.1", clientIP)
+	clientIP, _ = entries[1].Field("client_ip")
+	require.Equal(t, "203.0.113.7", clientIP, "XFF from an untrusted peer must be ignored")
+}
@@ -0,0 +1,38 @@
+package httpserver
+
+import (
+	"context"
+	"time"
+)
+
+// adaptiveShutdown computes the graceful shutdown timeout from the number of active connections.
+type adaptiveShutdown struct {
+	base    time.Duration
+	perConn time.Duration
+	max     time.Duration
+}
+
+// timeout returns base + perConn*active, capped at max.
+func (a *adaptiveShutdown) timeout(active int) time.Duration {
+	d := a.base + a.perConn*time.Duration(active)
+	if d > a.max || d < 0 {
+		// A negative value means an overflow
+		return a.max
+	}
+	return d
+}
+
+// gracefulShutdownTimeout returns the timeout of the automatic graceful shutdown, on a signal or the context cancellation:
+// the one computed from the active connections if WithAdaptiveShutdownTimeout is used, or the fixed one otherwise.
+func (s *Server) gracefulShutdownTimeout(ctx context.Context) time.Duration {
+	if s.adaptiveShutdown == nil {
+		return s.shutdownTimeout
+	}
+	active := s.Stats().ActiveConnections
+	timeout := s.adaptiveShutdown.timeout(active)
+	s.log.InfoContext(ctx, "computed adaptive shutdown timeout",
+		"active_connections", active,
+		"timeout", timeout,
+	)
+	return timeout
+}
@@ -0,0 +1,55 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+)
+
+// auditBodyLimit is the maximum number of request body bytes captured by AuditBodyMiddleware.
+const auditBodyLimit = 64 << 10 // 64 KB
+
+// AuditBodyMiddleware captures the body of the requests accepted by the matcher, e.g. the payment endpoints,
+// and hands it to the sink with the request context once the handler returns, e.g. to write an audit log.
+// The body is teed while the handler reads it, so the handler sees the full body as usual.
+//
+// Only the bytes the handler has read are captured, up to 64 KB; the rest is not buffered,
+// so the memory use stays bounded for large uploads. The sink is called synchronously,
+// also when the body is empty, so slow sinks should hand the data over to a background worker.
+// The captured slice is not reused and may be retained by the sink.
+func AuditBodyMiddleware(matcher func(*http.Request) bool, sink func(ctx context.Context, body []byte)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || !matcher(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			captured := &limitedBuffer{limit: auditBodyLimit}
+			body := r.Body
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.TeeReader(body, captured), body}
+			defer func() { sink(r.Context(), captured.buf.Bytes()) }()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limitedBuffer is an io.Writer keeping up to limit bytes and silently discarding the rest,
+// so that writes through an io.TeeReader never fail.
+type limitedBuffer struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+// Write keeps as much of p as fits the limit and always reports it as fully written.
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
@@ -0,0 +1,66 @@
+package httpserver_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditBodyMiddleware(t *testing.T) {
+	type ctxKey struct{}
+	var (
+		captured [][]byte
+		values   []interface{}
+	)
+	middleware := httpserver.AuditBodyMiddleware(
+		func(r *http.Request) bool { return strings.HasPrefix(r.URL.Path, "/payments") },
+		func(ctx context.Context, body []byte) {
+			captured = append(captured, body)
+			values = append(values, ctx.Value(ctxKey{}))
+		},
+	)
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		_, _ = w.Write(body)
+	}))
+
+	serve := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), ctxKey{}, "request-1"))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("handler sees the full body", func(t *testing.T) {
+		captured, values = nil, nil
+		rec := serve("/payments", `{"amount":100}`)
+		require.Equal(t, `{"amount":100}`, rec.Body.String())
+		require.Equal(t, [][]byte{[]byte(`{"amount":100}`)}, captured)
+		require.Equal(t, []interface{}{"request-1"}, values)
+	})
+
+	t.Run("captured size is capped", func(t *testing.T) {
+		captured = nil
+		body := strings.Repeat("a", 100<<10)
+		rec := serve("/payments/bulk", body)
+		require.Equal(t, body, rec.Body.String())
+		require.Len(t, captured, 1)
+		require.Len(t, captured[0], 64<<10)
+		require.Equal(t, body[:64<<10], string(captured[0]))
+	})
+
+	t.Run("non-matching request", func(t *testing.T) {
+		captured = nil
+		rec := serve("/users", "data")
+		require.Equal(t, "data", rec.Body.String())
+		require.Empty(t, captured)
+	})
+}
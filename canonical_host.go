@@ -0,0 +1,41 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// CanonicalHostMiddleware redirects requests which were not sent to the canonical host,
+// or not over HTTPS if forceHTTPS is set, to the canonical form of the URL, e.g.
+// "http://www.example.com/a?b=c" to "https://example.com/a?b=c", to consolidate SEO and avoid duplicate content.
+// The path and the query are preserved. GET and HEAD requests are redirected with 301 Moved Permanently,
+// other methods with 308 Permanent Redirect, so that the method and the body are kept.
+//
+// The canonical host may include a port, e.g. "localhost:8443"; the comparison with the request host ignores ports.
+// The X-Forwarded-Proto and X-Forwarded-Host headers are used only if the immediate peer
+// belongs to one of the trusted proxy prefixes; otherwise they are ignored, since anyone can send them.
+func CanonicalHostMiddleware(canonicalHost string, forceHTTPS bool, trustedProxies ...netip.Prefix) func(http.Handler) http.Handler {
+	canonicalHost = strings.ToLower(strings.TrimSpace(canonicalHost))
+	canonicalName := stripPort(canonicalHost)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scheme := requestScheme(r, trustedProxies)
+			hostMatches := requestHost(r, trustedProxies) == canonicalName
+			if hostMatches && (!forceHTTPS || scheme == "https") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if forceHTTPS {
+				scheme = "https"
+			}
+			status := http.StatusPermanentRedirect
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				status = http.StatusMovedPermanently
+			}
+			http.Redirect(w, r, scheme+"://"+canonicalHost+r.URL.RequestURI(), status)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package httpserver_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalHostMiddleware(t *testing.T) {
+	handler := httpserver.CanonicalHostMiddleware("example.com", true, netip.MustParsePrefix("10.0.0.0/8"))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		remote   string
+		tls      bool
+		headers  map[string]string
+		status   int
+		location string
+	}{
+		{name: "http to https", target: "http://example.com/a?b=c", status: http.StatusMovedPermanently, location: "https://example.com/a?b=c"},
+		{name: "www to apex", target: "https://www.example.com/a?b=c", tls: true, status: http.StatusMovedPermanently, location: "https://example.com/a?b=c"},
+		{name: "http www", target: "http://www.example.com/", status: http.StatusMovedPermanently, location: "https://example.com/"},
+		{name: "post keeps method", method: http.MethodPost, target: "http://example.com/form", status: http.StatusPermanentRedirect, location: "https://example.com/form"},
+		{name: "canonical", target: "https://example.com/a", tls: true, status: http.StatusOK},
+		{name: "canonical with port", target: "https://EXAMPLE.com:443/a", tls: true, status: http.StatusOK},
+		{
+			name: "trusted proxy terminating TLS", target: "http://example.com/a", remote: "10.0.0.1:1234",
+			headers: map[string]string{"X-Forwarded-Proto": "https"}, status: http.StatusOK,
+		},
+		{
+			name: "trusted proxy forwarding www", target: "http://internal/a", remote: "10.0.0.1:1234",
+			headers: map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "www.example.com"},
+			status:  http.StatusMovedPermanently, location: "https://example.com/a",
+		},
+		{
+			name: "untrusted forwarded headers", target: "http://example.com/a", remote: "192.0.2.1:1234",
+			headers: map[string]string{"X-Forwarded-Proto": "https"}, status: http.StatusMovedPermanently, location: "https://example.com/a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, tt.target, nil)
+			if tt.remote != "" {
+				req.RemoteAddr = tt.remote
+			}
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			} else {
+				req.TLS = nil
+			}
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.status, rec.Code)
+			require.Equal(t, tt.location, rec.Header().Get("Location"))
+		})
+	}
+
+	t.Run("without forcing https", func(t *testing.T) {
+		handler := httpserver.CanonicalHostMiddleware("example.com", false)(http.NotFoundHandler())
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://www.example.com/a", nil))
+		require.Equal(t, http.StatusMovedPermanently, rec.Code)
+		require.Equal(t, "http://example.com/a", rec.Header().Get("Location"))
+	})
+}
@@ -0,0 +1,87 @@
+package httpserver_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+
+	"github.com/dmitrymomot/httpserver"
+)
+
+func TestChiRouter(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	var routedBeforeServerMiddleware atomic.Bool
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Chi-Middleware", "1")
+			next.ServeHTTP(w, r)
+		})
+	})
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, hasDeadline := r.Context().Deadline()
+		_, _ = fmt.Fprintf(w, "user %s, deadline %t", chi.URLParam(r, "id"), hasDeadline)
+	})
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "custom not found", http.StatusNotFound)
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "custom method not allowed", http.StatusMethodNotAllowed)
+	})
+
+	server, err := httpserver.New(ln.Addr().String(), r,
+		httpserver.WithListener(ln),
+		httpserver.WithShutdownResponder(),
+		httpserver.WithRequestTimeout(time.Second),
+		httpserver.WithMiddleware(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// The route is resolved by chi after the server middlewares run
+				if chi.RouteContext(r.Context()) != nil {
+					routedBeforeServerMiddleware.Store(true)
+				}
+				next.ServeHTTP(w, r)
+			})
+		}),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = server.Start(ctx) }()
+
+	do := func(method, path string) (*http.Response, string) {
+		req, err := http.NewRequest(method, "http://"+ln.Addr().String()+path, nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, strings.TrimSpace(string(body))
+	}
+
+	resp, body := do(http.MethodGet, "/users/42")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "user 42, deadline true", body)
+	require.Equal(t, "1", resp.Header.Get("X-Chi-Middleware"))
+	require.False(t, routedBeforeServerMiddleware.Load(), "server middlewares run before chi routing")
+
+	resp, body = do(http.MethodGet, "/missing")
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.Equal(t, "custom not found", body)
+
+	resp, body = do(http.MethodPost, "/users/42")
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	require.Equal(t, "custom method not allowed", body)
+}
@@ -0,0 +1,148 @@
+package httpserver
+
+import (
+	"compress/gzip"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// Content codings understood by the package.
+const (
+	encodingIdentity = "identity"
+	encodingGzip     = "gzip"
+)
+
+// CompressionOptions configures response compression.
+// It is shared by all the compression features of the package.
+type CompressionOptions struct {
+	// Level is the gzip compression level, from 1 (best speed) to 9 (best compression).
+	Level int
+	// MinSize is the minimum response size in bytes to be compressed.
+	// Smaller responses are sent as is, since compression would not pay off.
+	MinSize int
+	// ContentTypes is the set of compressible media types, e.g. "text/html".
+	// A wildcard subtype, e.g. "text/*", matches all the subtypes of the type.
+	ContentTypes []string
+	// ExcludedPaths is the list of URL path prefixes which are never compressed.
+	ExcludedPaths []string
+}
+
+// DefaultCompressionOptions returns the compression options with sensible defaults:
+// level 5, 1KB minimum size and the common text-based content types.
+func DefaultCompressionOptions() CompressionOptions {
+	return CompressionOptions{
+		Level:   5,
+		MinSize: 1024,
+		ContentTypes: []string{
+			"text/*",
+			"application/json",
+			"application/javascript",
+			"application/xml",
+			"application/xhtml+xml",
+			"application/rss+xml",
+			"application/atom+xml",
+			"application/manifest+json",
+			"application/wasm",
+			"image/svg+xml",
+		},
+	}
+}
+
+// Validate checks that the compression level is in range and that all the content types are valid media types.
+func (o CompressionOptions) Validate() error {
+	if o.Level < gzip.BestSpeed || o.Level > gzip.BestCompression {
+		return fmt.Errorf("%w: %d", ErrInvalidCompressionLevel, o.Level)
+	}
+	if o.MinSize < 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidCompressionMinSize, o.MinSize)
+	}
+	for _, ct := range o.ContentTypes {
+		mediaType, params, err := mime.ParseMediaType(ct)
+		if err != nil || len(params) > 0 || !strings.Contains(mediaType, "/") || strings.HasPrefix(mediaType, "*") {
+			return fmt.Errorf("%w: %q", ErrInvalidContentType, ct)
+		}
+	}
+	return nil
+}
+
+// compressible reports whether a response with the given Content-Type header value should be compressed.
+func (o CompressionOptions) compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, ct := range o.ContentTypes {
+		ct = strings.ToLower(ct)
+		if ct == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(ct, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// excluded reports whether responses for the given URL path must not be compressed.
+func (o CompressionOptions) excluded(path string) bool {
+	for _, p := range o.ExcludedPaths {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiateEncoding selects the content coding for a response
+// based on the Accept-Encoding header value and the codings offered by the server.
+// The offers are listed in order of server preference and must not include identity,
+// which is always considered as the last resort unless the client explicitly forbids it
+// with "identity;q=0" or "*;q=0".
+//
+// It returns the selected coding, or false if none of the offers nor identity is acceptable,
+// in which case the response should be 406 Not Acceptable.
+// An empty header means the client has no preference, so identity is selected.
+func negotiateEncoding(header string, offers []string) (string, bool) {
+	accepted := parseQualityList(header)
+	if len(accepted) == 0 {
+		return encodingIdentity, true
+	}
+
+	quality := func(coding string) (float64, bool) {
+		wildcard, hasWildcard := 0.0, false
+		for _, a := range accepted {
+			if a.value == coding {
+				return a.q, true
+			}
+			if a.value == "*" {
+				wildcard, hasWildcard = a.q, true
+			}
+		}
+		return wildcard, hasWildcard
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q, ok := quality(strings.ToLower(offer)); ok && q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+
+	// Identity is acceptable unless explicitly excluded.
+	identityQ, ok := quality(encodingIdentity)
+	if !ok {
+		identityQ = 1
+		if bestQ > 0 {
+			// Prefer compression when identity is only implicitly acceptable.
+			identityQ = 0
+		}
+	}
+	if identityQ > bestQ {
+		return encodingIdentity, true
+	}
+	if best != "" {
+		return best, true
+	}
+	return "", false
+}
@@ -0,0 +1,210 @@
+package httpserver
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CompressionMiddleware compresses response bodies with gzip for clients which accept it,
+// e.g. for JSON API handlers. It is the handler-level counterpart of the static handler compression
+// and shares the CompressionOptions with it.
+//
+// Only responses with a compressible content type (see CompressionOptions.ContentTypes) are compressed,
+// so already compressed formats, e.g. images or archives, are sent as is, as well as responses
+// which already have a Content-Encoding. Bodies smaller than MinSize are buffered and sent uncompressed,
+// unless the handler flushes the response first, in which case the response is compressed right away
+// and every flush sends the compressed data written so far, which keeps streaming responses working.
+// Missing Content-Type headers are detected from the first bytes of the body.
+// Clients accepting neither gzip nor identity, e.g. with "identity;q=0", get 406 Not Acceptable.
+//
+// The options must be valid, otherwise the function panics; see CompressionOptions.Validate.
+func CompressionMiddleware(opts CompressionOptions) func(http.Handler) http.Handler {
+	if err := opts.Validate(); err != nil {
+		panic(err)
+	}
+	writers := &sync.Pool{
+		New: func() any {
+			zw, _ := gzip.NewWriterLevel(io.Discard, opts.Level)
+			return zw
+		},
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if opts.excluded(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding, ok := negotiateEncoding(r.Header.Get("Accept-Encoding"), []string{encodingGzip})
+			if !ok {
+				renderError(w, r, http.StatusNotAcceptable, statusError(http.StatusNotAcceptable))
+				return
+			}
+			if encoding != encodingGzip || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, opts: opts, writers: writers, status: http.StatusOK}
+			defer cw.close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// compressWriter buffers the beginning of the response body to decide whether to compress it.
+type compressWriter struct {
+	http.ResponseWriter
+	opts    CompressionOptions
+	writers *sync.Pool
+
+	status      int
+	wroteHeader bool // the handler has called WriteHeader
+	decided     bool // the header has been sent, with or without compression
+	buf         []byte
+	zw          *gzip.Writer
+}
+
+// WriteHeader records the status code; the header is sent once the compression is decided.
+func (w *compressWriter) WriteHeader(status int) {
+	if w.wroteHeader || w.decided {
+		return
+	}
+	// Informational responses do not complete the header
+	if status >= 100 && status < 200 && status != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	if !bodyAllowed(status) {
+		w.decide(false)
+	}
+}
+
+// Write buffers the data until the compression is decided, then writes it through the gzip writer if needed.
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.opts.MinSize {
+			return len(b), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.zw != nil {
+		return w.zw.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush compresses the response right away if it is compressible, and sends the data written so far.
+func (w *compressWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		_ = w.decide(true)
+	}
+	if w.zw != nil {
+		_ = w.zw.Flush()
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack lets the caller take over the connection.
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the original http.ResponseWriter, so that http.ResponseController can reach it.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide sends the header, compressing the response if large is set and the response qualifies,
+// and writes the buffered data.
+func (w *compressWriter) decide(large bool) error {
+	w.decided = true
+	h := w.Header()
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 && bodyAllowed(w.status) {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	if large && w.compressible() {
+		zw := w.writers.Get().(*gzip.Writer)
+		zw.Reset(w.ResponseWriter)
+		w.zw = zw
+
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", encodingGzip)
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			// The compressed representation is not byte-identical anymore
+			h.Set("ETag", "W/"+etag)
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) == 0 {
+		return nil
+	}
+	buf := w.buf
+	w.buf = nil
+	if w.zw != nil {
+		_, err := w.zw.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// compressible reports whether the response qualifies for compression.
+func (w *compressWriter) compressible() bool {
+	h := w.Header()
+	if !bodyAllowed(w.status) || w.status == http.StatusPartialContent {
+		return false
+	}
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	if cl, err := strconv.Atoi(h.Get("Content-Length")); err == nil && cl < w.opts.MinSize {
+		return false
+	}
+	return w.opts.compressible(h.Get("Content-Type"))
+}
+
+// close completes the response once the handler returns.
+func (w *compressWriter) close() {
+	if !w.decided {
+		if !w.wroteHeader && len(w.buf) == 0 {
+			// Nothing written, let net/http send the default response
+			return
+		}
+		// The body is smaller than the minimum size
+		_ = w.decide(false)
+	}
+	if w.zw != nil {
+		_ = w.zw.Close()
+		w.zw.Reset(io.Discard)
+		w.writers.Put(w.zw)
+		w.zw = nil
+	}
+}
+
+// bodyAllowed reports whether a response with the given status can have a body.
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
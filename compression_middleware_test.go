@@ -0,0 +1,138 @@
+package httpserver_test
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressionMiddleware(t *testing.T) {
+	large := strings.Repeat("value ", 500)
+	handler := httpserver.CompressionMiddleware(httpserver.DefaultCompressionOptions())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]string{"data": large})
+		case "/small":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"ok":true}`))
+		case "/png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte(large))
+		case "/sniff":
+			_, _ = w.Write([]byte("<html>" + large + "</html>"))
+		case "/no-content":
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+
+	get := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	decode := func(t *testing.T, rec *httptest.ResponseRecorder) string {
+		zr, err := gzip.NewReader(rec.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	t.Run("compressed", func(t *testing.T) {
+		rec := get("/json", "gzip, br")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+		require.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+		require.Empty(t, rec.Header().Get("Content-Length"))
+		require.Contains(t, decode(t, rec), large)
+	})
+
+	t.Run("not accepted", func(t *testing.T) {
+		rec := get("/json", "")
+		require.Empty(t, rec.Header().Get("Content-Encoding"))
+		require.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+		require.Contains(t, rec.Body.String(), large)
+	})
+
+	t.Run("no acceptable encoding", func(t *testing.T) {
+		rec := get("/json", "identity;q=0")
+		require.Equal(t, http.StatusNotAcceptable, rec.Code)
+		require.Empty(t, rec.Header().Get("Content-Encoding"))
+		require.NotContains(t, rec.Body.String(), large)
+	})
+
+	t.Run("small body is skipped", func(t *testing.T) {
+		rec := get("/small", "gzip")
+		require.Empty(t, rec.Header().Get("Content-Encoding"))
+		require.Equal(t, `{"ok":true}`, rec.Body.String())
+	})
+
+	t.Run("already compressed type is skipped", func(t *testing.T) {
+		rec := get("/png", "gzip")
+		require.Empty(t, rec.Header().Get("Content-Encoding"))
+		require.Equal(t, large, rec.Body.String())
+	})
+
+	t.Run("sniffed content type", func(t *testing.T) {
+		rec := get("/sniff", "gzip")
+		require.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+		require.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	})
+
+	t.Run("no content", func(t *testing.T) {
+		rec := get("/no-content", "gzip")
+		require.Equal(t, http.StatusNoContent, rec.Code)
+		require.Empty(t, rec.Header().Get("Content-Encoding"))
+	})
+}
+
+func TestCompressionMiddlewareStreaming(t *testing.T) {
+	next := make(chan struct{})
+	srv := httptest.NewServer(httpserver.CompressionMiddleware(httpserver.DefaultCompressionOptions())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 3; i++ {
+			_, _ = w.Write([]byte("data: event\n\n"))
+			w.(http.Flusher).Flush()
+			<-next
+		}
+	})))
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+
+	// Every event is readable before the handler writes the next one
+	zr, err := gzip.NewReader(resp.Body)
+	require.NoError(t, err)
+	lines := bufio.NewReader(zr)
+	for i := 0; i < 3; i++ {
+		line, err := lines.ReadString('\n')
+		require.NoError(t, err)
+		require.Equal(t, "data: event\n", line)
+		_, err = lines.ReadString('\n')
+		require.NoError(t, err)
+		next <- struct{}{}
+	}
+}
+
+func TestCompressionMiddlewareInvalidOptions(t *testing.T) {
+	opts := httpserver.DefaultCompressionOptions()
+	opts.Level = 42
+	require.Panics(t, func() { httpserver.CompressionMiddleware(opts) })
+}
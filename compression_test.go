@@ -0,0 +1,69 @@
+package httpserver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	offers := []string{encodingGzip}
+
+	tests := []struct {
+		header   string
+		expected string
+		ok       bool
+	}{
+		{header: "", expected: encodingIdentity, ok: true},
+		{header: "gzip", expected: encodingGzip, ok: true},
+		{header: "GZIP", expected: encodingGzip, ok: true},
+		{header: "deflate, gzip;q=0.5", expected: encodingGzip, ok: true},
+		{header: "gzip;q=0", expected: encodingIdentity, ok: true},
+		{header: "gzip;q=0.5, identity", expected: encodingIdentity, ok: true},
+		{header: "identity;q=0, gzip", expected: encodingGzip, ok: true},
+		{header: "identity;q=0, br", expected: "", ok: false},
+		{header: "*", expected: encodingGzip, ok: true},
+		{header: "*;q=0", expected: "", ok: false},
+		{header: "*;q=0, identity", expected: encodingIdentity, ok: true},
+		{header: "*;q=0.2, gzip;q=0", expected: encodingIdentity, ok: true},
+		{header: "br", expected: encodingIdentity, ok: true},
+		{header: "gzip;q=invalid", expected: encodingIdentity, ok: true},
+		{header: " gzip ; q=0.8 , identity;q=0.1", expected: encodingGzip, ok: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			encoding, ok := negotiateEncoding(tt.header, offers)
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.expected, encoding)
+		})
+	}
+}
+
+func TestCompressionOptions(t *testing.T) {
+	t.Run("defaults are valid", func(t *testing.T) {
+		require.NoError(t, DefaultCompressionOptions().Validate())
+	})
+
+	t.Run("invalid level", func(t *testing.T) {
+		opts := DefaultCompressionOptions()
+		opts.Level = 10
+		require.ErrorIs(t, opts.Validate(), ErrInvalidCompressionLevel)
+	})
+
+	t.Run("invalid content type", func(t *testing.T) {
+		for _, ct := range []string{"json", "*/*", "text/html; charset=utf-8", ""} {
+			opts := DefaultCompressionOptions()
+			opts.ContentTypes = append(opts.ContentTypes, ct)
+			require.ErrorIs(t, opts.Validate(), ErrInvalidContentType, ct)
+		}
+	})
+
+	t.Run("compressible", func(t *testing.T) {
+		opts := DefaultCompressionOptions()
+		require.True(t, opts.compressible("text/html; charset=utf-8"))
+		require.True(t, opts.compressible("application/json"))
+		require.False(t, opts.compressible("image/png"))
+		require.False(t, opts.compressible(""))
+	})
+}
@@ -0,0 +1,60 @@
+package httpserver
+
+import (
+	"net/http"
+)
+
+type concurrencyLimitOption func(*concurrencyLimitConfig)
+
+// concurrencyLimitConfig holds the configuration of the concurrency limit middleware.
+type concurrencyLimitConfig struct {
+	overLimit http.Handler
+}
+
+// WithOverLimitHandler sets the handler for requests exceeding the concurrency limit,
+// e.g. to render a friendly "try again" page or to redirect to a waiting room,
+// instead of the default plain-text 503 Service Unavailable.
+// The Retry-After header is set to 1 second before the handler runs; the handler may override or remove it.
+func WithOverLimitHandler(h http.Handler) concurrencyLimitOption {
+	return func(cfg *concurrencyLimitConfig) {
+		cfg.overLimit = h
+	}
+}
+
+// ConcurrencyLimitMiddleware limits the number of requests handled concurrently by the wrapped handler.
+// Requests exceeding the limit are not queued but rejected immediately
+// with 503 Service Unavailable and the Retry-After header, or passed to the handler set with WithOverLimitHandler.
+//
+// Unlike limiting connections at the listener level, this works per request,
+// so it also bounds requests multiplexed over keep-alive and HTTP/2 connections.
+// A non-positive limit disables the limit.
+func ConcurrencyLimitMiddleware(limit int, opts ...concurrencyLimitOption) func(http.Handler) http.Handler {
+	cfg := &concurrencyLimitConfig{}
+	for _, o := range opts {
+		o(cfg)
+	}
+	overLimit := cfg.overLimit
+	if overLimit == nil {
+		overLimit = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			renderError(w, r, http.StatusServiceUnavailable, statusError(http.StatusServiceUnavailable))
+		})
+	}
+
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+
+		sem := make(chan struct{}, limit)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+				next.ServeHTTP(w, r)
+			default:
+				w.Header().Set("Retry-After", "1")
+				overLimit.ServeHTTP(w, r)
+			}
+		})
+	}
+}
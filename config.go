@@ -0,0 +1,152 @@
+package httpserver
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// ServerConfig is a snapshot of the effective server configuration, after all the options are applied,
+// e.g. to log it at startup or to attach it to a support ticket. Sensitive data, like TLS certificates
+// and keys, is never included; only the TLS summary is.
+// In JSON, durations are encoded as strings, e.g. "5s".
+type ServerConfig struct {
+	Addr              string        `json:"addr"`
+	ReadTimeout       time.Duration `json:"read_timeout"`
+	ReadHeaderTimeout time.Duration `json:"read_header_timeout"`
+	WriteTimeout      time.Duration `json:"write_timeout"`
+	IdleTimeout       time.Duration `json:"idle_timeout"`
+	MaxHeaderBytes    int           `json:"max_header_bytes"`
+	// MaxHeaderValueLength is the limit set with WithMaxHeaderValueLength, or zero if it is not used.
+	MaxHeaderValueLength int `json:"max_header_value_length"`
+
+	// TLSEnabled reports whether a TLS configuration with certificates is set.
+	TLSEnabled bool `json:"tls_enabled"`
+	// TLSCertificates is the number of the static TLS certificates; certificates provided by callbacks are not counted.
+	TLSCertificates int `json:"tls_certificates"`
+	// TLSMinVersion is the minimum TLS version, e.g. "TLS 1.2", or empty if the crypto/tls default is used.
+	TLSMinVersion string `json:"tls_min_version,omitempty"`
+
+	ShutdownTimeout        time.Duration `json:"shutdown_timeout"`
+	GracefulFraction       float64       `json:"graceful_fraction"`
+	RequestTimeout         time.Duration `json:"request_timeout"`
+	AbsoluteRequestTimeout time.Duration `json:"absolute_request_timeout"`
+	HTTP2StreamTimeout     time.Duration `json:"http2_stream_timeout"`
+	ShutdownDelay          time.Duration `json:"shutdown_delay"`
+	// NonCriticalShutdownTimeout is the timeout set with WithCriticalRequestDraining, or zero if it is not used.
+	NonCriticalShutdownTimeout time.Duration `json:"non_critical_shutdown_timeout"`
+	MaxDecompressedSize        int64         `json:"max_decompressed_size"`
+	MaxIdleConnections         int           `json:"max_idle_connections"`
+
+	// AdditionalListeners are the addresses set with WithAdditionalListener.
+	AdditionalListeners []string `json:"additional_listeners,omitempty"`
+	// HealthChecks are the names of the checks registered with WithHealthCheck.
+	HealthChecks []string `json:"health_checks,omitempty"`
+	// Features are the names of the enabled options without a value of their own, e.g. "WithShutdownResponder", sorted.
+	Features []string `json:"features,omitempty"`
+}
+
+// Config returns a snapshot of the effective server configuration.
+// It reflects the resolved settings, since options may override each other,
+// e.g. WithPreconfiguredServer and the timeout options.
+func (s *Server) Config() ServerConfig {
+	hs := s.httpServer
+	cfg := ServerConfig{
+		Addr:                   hs.Addr,
+		ReadTimeout:            hs.ReadTimeout,
+		ReadHeaderTimeout:      hs.ReadHeaderTimeout,
+		WriteTimeout:           hs.WriteTimeout,
+		IdleTimeout:            hs.IdleTimeout,
+		MaxHeaderBytes:         hs.MaxHeaderBytes,
+		MaxHeaderValueLength:   max(s.maxHeaderValueLen, 0),
+		TLSEnabled:             hasTLSCertificates(hs.TLSConfig),
+		ShutdownTimeout:        s.shutdownTimeout,
+		GracefulFraction:       s.gracefulFraction,
+		RequestTimeout:         s.requestTimeout,
+		AbsoluteRequestTimeout: s.absoluteRequestTimeout,
+		HTTP2StreamTimeout:     s.streamTimeout,
+		ShutdownDelay:          s.shutdownDelay,
+		MaxDecompressedSize:    s.maxDecompressedSize,
+		MaxIdleConnections:     s.maxIdleConns,
+	}
+	if s.priorities != nil {
+		cfg.NonCriticalShutdownTimeout = s.nonCriticalTimeout
+	}
+	if hs.TLSConfig != nil {
+		cfg.TLSCertificates = len(hs.TLSConfig.Certificates)
+		if hs.TLSConfig.MinVersion != 0 {
+			cfg.TLSMinVersion = tls.VersionName(hs.TLSConfig.MinVersion)
+		}
+	}
+	for _, al := range s.listeners {
+		cfg.AdditionalListeners = append(cfg.AdditionalListeners, al.addr)
+	}
+	for _, c := range s.healthChecks {
+		cfg.HealthChecks = append(cfg.HealthChecks, c.name)
+	}
+
+	for name, enabled := range map[string]bool{
+		"WithStrictTLS":               s.strictTLS,
+		"WithInflightTracking":        s.inflight != nil,
+		"WithShutdownResponder":       s.shutdownResponse != nil,
+		"WithShutdownSafeResponses":   s.shutdownSafeResponses,
+		"WithForceQuitOnSecondSignal": s.forceQuitOnSecondSignal,
+		"WithSignalHandler":           len(s.signalActions) > 0,
+		"WithBaseParentContext":       s.baseParent != nil,
+		"WithRequestStats":            s.requestStats,
+		"WithShutdownOnUnhealthy":     s.unhealthyCheck != "",
+		"WithAdaptiveShutdownTimeout": s.adaptiveShutdown != nil,
+		"WithAcceptErrorHandler":      s.acceptErrorHandler != nil,
+		"WithInstrumentation":         s.instrumentation != nil,
+		"WithListener":                s.listener != nil,
+		"WithListenConfig":            s.listenConfig != nil,
+		"WithMiddleware":              len(s.middlewares) > 0,
+		"WithStartupValidation":       len(s.startupValidators) > 0,
+		"WithReadinessFile":           s.readinessFile != "",
+		"WithConnStateLogging":        s.connStateLogging,
+		"WithResponseHeaders":         len(s.responseHeaders) > 0,
+		"WithClientIPLogging":         s.clientIPLogging,
+		"WithErrorRenderer":           s.errorRenderer != nil,
+		"WithTCPNoDelay":              s.tcpNoDelay != nil,
+		"WithShutdownNotify":          len(s.shutdownNotify) > 0,
+		"WithSocketBufferSizes":       s.readBufferSize > 0 || s.writeBufferSize > 0,
+	} {
+		if enabled {
+			cfg.Features = append(cfg.Features, name)
+		}
+	}
+	sort.Strings(cfg.Features)
+
+	return cfg
+}
+
+// MarshalJSON encodes the configuration with the durations as strings, e.g. "5s".
+func (c ServerConfig) MarshalJSON() ([]byte, error) {
+	type config ServerConfig
+	return json.Marshal(struct {
+		config
+		ReadTimeout            string `json:"read_timeout"`
+		ReadHeaderTimeout      string `json:"read_header_timeout"`
+		WriteTimeout           string `json:"write_timeout"`
+		IdleTimeout            string `json:"idle_timeout"`
+		ShutdownTimeout        string `json:"shutdown_timeout"`
+		RequestTimeout         string `json:"request_timeout"`
+		AbsoluteRequestTimeout string `json:"absolute_request_timeout"`
+		HTTP2StreamTimeout     string `json:"http2_stream_timeout"`
+		ShutdownDelay          string `json:"shutdown_delay"`
+		NonCriticalTimeout     string `json:"non_critical_shutdown_timeout"`
+	}{
+		config:                 config(c),
+		ReadTimeout:            c.ReadTimeout.String(),
+		ReadHeaderTimeout:      c.ReadHeaderTimeout.String(),
+		WriteTimeout:           c.WriteTimeout.String(),
+		IdleTimeout:            c.IdleTimeout.String(),
+		ShutdownTimeout:        c.ShutdownTimeout.String(),
+		RequestTimeout:         c.RequestTimeout.String(),
+		AbsoluteRequestTimeout: c.AbsoluteRequestTimeout.String(),
+		HTTP2StreamTimeout:     c.HTTP2StreamTimeout.String(),
+		ShutdownDelay:          c.ShutdownDelay.String(),
+		NonCriticalTimeout:     c.NonCriticalShutdownTimeout.String(),
+	})
+}
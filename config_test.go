@@ -0,0 +1,57 @@
+package httpserver_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerConfig(t *testing.T) {
+	certPEM, keyPEM := generateTestCert(t)
+	tlsConfig, err := httpserver.TLSConfigFromPEM(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	server, err := httpserver.New("localhost:8443", http.NotFoundHandler(),
+		httpserver.WithPreconfiguredServer(&http.Server{
+			Addr:        "localhost:8443",
+			Handler:     http.NotFoundHandler(),
+			ReadTimeout: time.Second,
+		}),
+		httpserver.WithWriteTimeout(20*time.Second),
+		httpserver.WithTLSConfig(tlsConfig),
+		httpserver.WithGracefulShutdown(30*time.Second),
+		httpserver.WithRequestTimeout(3*time.Second),
+		httpserver.WithAdditionalListener("localhost:8080", nil, nil),
+		httpserver.WithHealthCheck("db", nil),
+		httpserver.WithShutdownResponder(),
+		httpserver.WithStrictTLS(),
+	)
+	require.NoError(t, err)
+
+	cfg := server.Config()
+	require.Equal(t, "localhost:8443", cfg.Addr)
+	require.Equal(t, time.Second, cfg.ReadTimeout)
+	require.Equal(t, 20*time.Second, cfg.WriteTimeout)
+	require.Equal(t, 30*time.Second, cfg.ShutdownTimeout)
+	require.Equal(t, 3*time.Second, cfg.RequestTimeout)
+	require.True(t, cfg.TLSEnabled)
+	require.Equal(t, 1, cfg.TLSCertificates)
+	require.Equal(t, "TLS 1.2", cfg.TLSMinVersion)
+	require.Equal(t, []string{"localhost:8080"}, cfg.AdditionalListeners)
+	require.Equal(t, []string{"db"}, cfg.HealthChecks)
+	require.Equal(t, []string{"WithShutdownResponder", "WithStrictTLS"}, cfg.Features)
+
+	data, err := json.Marshal(cfg)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "PRIVATE KEY")
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, "20s", decoded["write_timeout"])
+	require.Equal(t, "30s", decoded["shutdown_timeout"])
+	require.Equal(t, true, decoded["tls_enabled"])
+}
@@ -0,0 +1,141 @@
+package httpserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// connInfo describes the current state of a tracked connection.
+type connInfo struct {
+	state http.ConnState
+	since time.Time
+}
+
+// connTracker keeps track of the server connections and their states using the http.Server ConnState hook.
+type connTracker struct {
+	mu    sync.Mutex
+	conns map[net.Conn]connInfo
+}
+
+// newConnTracker creates a new connection tracker.
+func newConnTracker() *connTracker {
+	return &connTracker{
+		conns: make(map[net.Conn]connInfo),
+	}
+}
+
+// track records the new state of the connection.
+func (t *connTracker) track(c net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch state {
+	case http.StateHijacked, http.StateClosed:
+		delete(t.conns, c)
+	default:
+		t.conns[c] = connInfo{state: state, since: time.Now()}
+	}
+}
+
+// closeIdle closes all the idle keep-alive connections and returns their number.
+func (t *connTracker) closeIdle() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var n int
+	for c, info := range t.conns {
+		if info.state == http.StateIdle {
+			_ = c.Close()
+			delete(t.conns, c)
+			n++
+		}
+	}
+	return n
+}
+
+// reapIdle closes the oldest idle keep-alive connections until at most max of them remain
+// and returns the closed connections along with how long they have been idle.
+func (t *connTracker) reapIdle(max int) map[net.Conn]time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var idle []net.Conn
+	for c, info := range t.conns {
+		if info.state == http.StateIdle {
+			idle = append(idle, c)
+		}
+	}
+	if len(idle) <= max {
+		return nil
+	}
+
+	sort.Slice(idle, func(i, j int) bool {
+		return t.conns[idle[i]].since.Before(t.conns[idle[j]].since)
+	})
+	reaped := make(map[net.Conn]time.Duration, len(idle)-max)
+	for _, c := range idle[:len(idle)-max] {
+		reaped[c] = time.Since(t.conns[c].since)
+		_ = c.Close()
+		delete(t.conns, c)
+	}
+	return reaped
+}
+
+// snapshot returns the states of the tracked connections.
+func (t *connTracker) snapshot() []connInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := make([]connInfo, 0, len(t.conns))
+	for _, info := range t.conns {
+		result = append(result, info)
+	}
+	return result
+}
+
+// setConnState installs the connection tracking hook,
+// chaining the ConnState hook of a preconfigured http.Server, if any.
+func (s *Server) setConnState() {
+	next := s.httpServer.ConnState
+	s.httpServer.ConnState = func(c net.Conn, state http.ConnState) {
+		s.conns.track(c, state)
+		if s.connStateLogging {
+			s.logDebug(context.Background(), "connection state changed",
+				"remote_addr", c.RemoteAddr().String(),
+				"state", state.String(),
+			)
+		}
+		if state == http.StateIdle && s.maxIdleConns > 0 {
+			s.reapIdleConnections()
+		}
+		s.observeTLSHandshake(c, state)
+		if next != nil {
+			next(c, state)
+		}
+	}
+}
+
+// reapIdleConnections closes the oldest idle keep-alive connections exceeding the limit set with WithMaxIdleConnections.
+func (s *Server) reapIdleConnections() {
+	for c, idleFor := range s.conns.reapIdle(s.maxIdleConns) {
+		s.logDebug(context.Background(), "reaped idle connection",
+			"remote_addr", c.RemoteAddr().String(),
+			"idle_for", idleFor,
+			"max_idle_connections", s.maxIdleConns,
+		)
+	}
+}
+
+// CloseIdleConnections closes the idle keep-alive connections of the server,
+// so that clients reconnect and pick up new settings, e.g. after a configuration reload.
+// Connections which are handling a request, or have not sent one yet, are left untouched.
+// A connection becoming active at the very moment it is closed may fail its request,
+// which clients usually retry for idempotent requests.
+func (s *Server) CloseIdleConnections() {
+	n := s.conns.closeIdle()
+	s.log.InfoContext(context.Background(), "closed idle connections", "count", n)
+}
@@ -0,0 +1,77 @@
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// RequireMaxContentLength rejects requests whose declared Content-Length exceeds n bytes
+// with 413 Request Entity Too Large, before the body is read.
+// Requests with a negative, malformed or ambiguous (several different values) Content-Length
+// are rejected with 400 Bad Request.
+//
+// Since clients may lie about the length or omit it, e.g. with chunked encoding,
+// the body is also wrapped with http.MaxBytesReader, so reading more than n bytes fails
+// with *http.MaxBytesError and the connection is closed after the response.
+// A non-positive n disables the limit.
+//
+// Clients uploading large bodies may send "Expect: 100-continue" and wait for the server
+// before sending the body. net/http sends "100 Continue" only when the handler starts reading the body,
+// so a request rejected by this middleware gets the 413 response right away and the body is never uploaded.
+// Place this and other middlewares rejecting requests early, e.g. authentication, before anything
+// which reads the body, such as form parsing. Expectations other than 100-continue are answered
+// with 417 Expectation Failed by net/http before any middleware runs.
+//
+// WithMaxDecompressedSize decodes gzip bodies before the WithMiddleware stack runs: it reads the gzip header,
+// which sends "100 Continue", and drops Content-Length. For such requests this middleware
+// cannot reject early and only caps the decoded body at n bytes.
+func RequireMaxContentLength(n int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if n <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			length, ok := declaredContentLength(r)
+			if !ok {
+				renderError(w, r, http.StatusBadRequest, errors.New("invalid Content-Length"))
+				return
+			}
+			if length > n {
+				renderError(w, r, http.StatusRequestEntityTooLarge, statusError(http.StatusRequestEntityTooLarge))
+				return
+			}
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = http.MaxBytesReader(w, r.Body, n)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// declaredContentLength returns the Content-Length declared by the request headers, or -1 if there is none.
+// It reports false if the header is malformed, negative or has several different values.
+func declaredContentLength(r *http.Request) (int64, bool) {
+	values := r.Header.Values("Content-Length")
+	if len(values) == 0 {
+		return r.ContentLength, true
+	}
+
+	length := int64(-1)
+	for _, v := range values {
+		// A single header may carry a comma-separated list of values
+		for _, part := range strings.Split(v, ",") {
+			part = strings.TrimSpace(part)
+			n, err := strconv.ParseUint(part, 10, 63)
+			if err != nil {
+				return 0, false
+			}
+			if length >= 0 && int64(n) != length {
+				return 0, false
+			}
+			length = int64(n)
+		}
+	}
+	return length, true
+}
@@ -0,0 +1,111 @@
+package httpserver_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireMaxContentLength(t *testing.T) {
+	handler := httpserver.RequireMaxContentLength(50)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+		require.NoError(t, err)
+		_, _ = w.Write(body)
+	}))
+
+	send := func(body string, contentLength int64, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.ContentLength = contentLength
+		for _, h := range header {
+			req.Header.Add("Content-Length", h)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("within limit", func(t *testing.T) {
+		rec := send("hello", 5, "5")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "hello", rec.Body.String())
+	})
+
+	t.Run("declared too large", func(t *testing.T) {
+		require.Equal(t, http.StatusRequestEntityTooLarge, send("hello", 100, "100").Code)
+	})
+
+	t.Run("declared small, actual too large", func(t *testing.T) {
+		require.Equal(t, http.StatusRequestEntityTooLarge, send(strings.Repeat("x", 100), 10, "10").Code)
+	})
+
+	t.Run("unknown length, actual too large", func(t *testing.T) {
+		require.Equal(t, http.StatusRequestEntityTooLarge, send(strings.Repeat("x", 100), -1).Code)
+	})
+
+	t.Run("invalid header", func(t *testing.T) {
+		for _, headers := range [][]string{{"-1"}, {"abc"}, {"1e3"}, {"5", "6"}, {"5, 6"}, {""}} {
+			require.Equal(t, http.StatusBadRequest, send("hello", 5, headers...).Code, headers)
+		}
+	})
+
+	t.Run("repeated identical values", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, send("hello", 5, "5", "5").Code)
+	})
+}
+
+func TestRequireMaxContentLengthExpectContinue(t *testing.T) {
+	srv := httptest.NewServer(httpserver.RequireMaxContentLength(1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &http.Transport{ExpectContinueTimeout: 5 * time.Second}}
+	send := func(size int) (int, int64) {
+		body := &countingReader{r: strings.NewReader(strings.Repeat("x", size))}
+		req, err := http.NewRequest(http.MethodPost, srv.URL, body)
+		require.NoError(t, err)
+		req.ContentLength = int64(size)
+		req.Header.Set("Expect", "100-continue")
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode, body.n.Load()
+	}
+
+	// The oversized body is rejected before the client sends it
+	code, sent := send(1 << 20)
+	require.Equal(t, http.StatusRequestEntityTooLarge, code)
+	require.Zero(t, sent)
+
+	// The accepted body is sent after 100 Continue
+	code, sent = send(512)
+	require.Equal(t, http.StatusNoContent, code)
+	require.Equal(t, int64(512), sent)
+}
+
+// countingReader counts the bytes read from the underlying reader.
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
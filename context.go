@@ -0,0 +1,66 @@
+package httpserver
+
+import (
+	"context"
+	"net"
+)
+
+// serverContextKey is the context key for the server instance.
+type serverContextKey struct{}
+
+// ServerFromContext returns the server handling the request from the request context.
+// It allows handlers and middlewares to query the server state, e.g. whether it is shutting down.
+// It returns false if the context does not belong to a request served by a Server.
+func ServerFromContext(ctx context.Context) (*Server, bool) {
+	s, ok := ctx.Value(serverContextKey{}).(*Server)
+	return s, ok
+}
+
+// setBaseContext makes the server instance available in every request context,
+// along with the handler of the additional listener which accepted the connection, if any.
+// The parent set with WithBaseParentContext, or else the BaseContext of a preconfigured http.Server, if any,
+// is preserved as the parent. The reference from the context to the server does not prevent garbage collection,
+// since contexts do not outlive the server connections.
+func (s *Server) setBaseContext() {
+	base := s.httpServer.BaseContext
+	s.httpServer.BaseContext = func(ln net.Listener) context.Context {
+		ctx := context.Background()
+		switch {
+		case s.baseParent != nil:
+			// Keep the values only, the cancellation initiates the graceful shutdown instead
+			ctx = context.WithoutCancel(s.baseParent)
+		case base != nil:
+			ctx = base(ln)
+		}
+		if hl, ok := ln.(*handlerListener); ok {
+			ctx = context.WithValue(ctx, listenerHandlerContextKey{}, hl.handler)
+		}
+		return context.WithValue(ctx, serverContextKey{}, s)
+	}
+}
+
+// MergeContexts returns a context which is cancelled as soon as any of the given contexts is done,
+// e.g. to shut the server down on either the application or a subsystem context.
+// Values are taken from the first context only; with no contexts, context.Background is used.
+// The returned cancel function releases the resources and must be called when the context is no longer needed.
+func MergeContexts(ctxs ...context.Context) (context.Context, func()) {
+	if len(ctxs) == 0 {
+		return context.WithCancel(context.Background())
+	}
+
+	ctx, cancel := context.WithCancelCause(ctxs[0])
+	stops := make([]func() bool, 0, len(ctxs)-1)
+	for _, c := range ctxs[1:] {
+		c := c
+		stops = append(stops, context.AfterFunc(c, func() {
+			cancel(context.Cause(c))
+		}))
+	}
+
+	return ctx, func() {
+		for _, stop := range stops {
+			stop()
+		}
+		cancel(context.Canceled)
+	}
+}
@@ -0,0 +1,90 @@
+package httpserver_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeContexts(t *testing.T) {
+	type key struct{}
+	appCtx, appCancel := context.WithCancel(context.WithValue(context.Background(), key{}, "app"))
+	defer appCancel()
+	subCtx, subCancel := context.WithCancel(context.Background())
+
+	ctx, cancel := httpserver.MergeContexts(appCtx, subCtx)
+	defer cancel()
+
+	require.Equal(t, "app", ctx.Value(key{}))
+	require.NoError(t, ctx.Err())
+
+	subCancel()
+	select {
+	case <-ctx.Done():
+		require.ErrorIs(t, ctx.Err(), context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("merged context was not cancelled")
+	}
+	require.NoError(t, appCtx.Err())
+}
+
+func TestBaseParentContext(t *testing.T) {
+	type key struct{}
+	parent, cancelParent := context.WithCancel(context.WithValue(context.Background(), key{}, "app"))
+	defer cancelParent()
+
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	values := make(chan interface{}, 2)
+	ctxErrs := make(chan error, 1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		values <- r.Context().Value(key{})
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-release
+			ctxErrs <- r.Context().Err()
+		}
+	})
+	server, err := httpserver.New(ln.Addr().String(), handler,
+		httpserver.WithListener(ln),
+		httpserver.WithBaseParentContext(parent),
+	)
+	require.NoError(t, err)
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- server.Start(context.Background()) }()
+
+	resp, err := http.Get("http://" + ln.Addr().String())
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, "app", <-values)
+
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/slow")
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+	require.Equal(t, "app", <-values)
+
+	// Cancelling the parent starts the graceful shutdown, without cancelling the in-flight request
+	cancelParent()
+	require.Eventually(t, server.ShuttingDown, time.Second, 10*time.Millisecond)
+	close(release)
+	require.NoError(t, <-ctxErrs)
+
+	select {
+	case err := <-stopped:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("server did not stop when the parent context was cancelled")
+	}
+}
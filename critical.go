@@ -0,0 +1,107 @@
+package httpserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// requestPriorityContextKey is the context key for the drain priority of a request.
+type requestPriorityContextKey struct{}
+
+// requestPriority is the drain priority of a request which is being handled.
+type requestPriority struct {
+	conn     net.Conn
+	critical atomic.Bool
+}
+
+// MarkCritical marks the request as critical for the graceful shutdown, e.g. a long-running report generation.
+// With WithCriticalRequestDraining, the shutdown waits the full timeout for critical requests,
+// while the connections handling only non-critical requests are closed sooner.
+// A request can be marked at any time while it is being handled; marking it after its connection
+// has been closed has no effect.
+//
+// It reports whether the request was marked, i.e. whether it is served by a Server with WithCriticalRequestDraining.
+func MarkCritical(r *http.Request) bool {
+	p, ok := r.Context().Value(requestPriorityContextKey{}).(*requestPriority)
+	if ok {
+		p.critical.Store(true)
+	}
+	return ok
+}
+
+// priorityTracker keeps track of the drain priorities of the requests which are being handled.
+type priorityTracker struct {
+	mu       sync.Mutex
+	requests map[*requestPriority]struct{}
+}
+
+// newPriorityTracker creates a new request priority tracker.
+func newPriorityTracker() *priorityTracker {
+	return &priorityTracker{
+		requests: make(map[*requestPriority]struct{}),
+	}
+}
+
+// add registers the request priority and returns a function which must be called when the request is completed.
+func (t *priorityTracker) add(p *requestPriority) func() {
+	t.mu.Lock()
+	t.requests[p] = struct{}{}
+	t.mu.Unlock()
+
+	return func() {
+		t.mu.Lock()
+		delete(t.requests, p)
+		t.mu.Unlock()
+	}
+}
+
+// closeNonCritical closes the connections handling only non-critical requests and returns their number.
+// Connections multiplexing several requests, i.e. HTTP/2 ones, are kept if any of their requests is critical.
+func (t *priorityTracker) closeNonCritical() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	critical := make(map[net.Conn]bool)
+	for p := range t.requests {
+		critical[p.conn] = critical[p.conn] || p.critical.Load()
+	}
+
+	var n int
+	for c, keep := range critical {
+		if !keep {
+			_ = c.Close()
+			n++
+		}
+	}
+	return n
+}
+
+// priorityMiddleware makes the request priority available to MarkCritical and tracks it until the request is completed.
+func (s *Server) priorityMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, ok := r.Context().Value(connContextKey{}).(net.Conn)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		p := &requestPriority{conn: conn}
+		done := s.priorities.add(p)
+		defer done()
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestPriorityContextKey{}, p)))
+	})
+}
+
+// closeNonCriticalRequests closes the connections of the non-critical requests
+// once the non-critical shutdown timeout set with WithCriticalRequestDraining has expired.
+func (s *Server) closeNonCriticalRequests(ctx context.Context) {
+	n := s.priorities.closeNonCritical()
+	s.log.InfoContext(ctx, "closed connections of non-critical requests",
+		"count", n,
+		"timeout", s.nonCriticalTimeout,
+	)
+}
@@ -0,0 +1,30 @@
+package httpserver
+
+import (
+	"net/http"
+	"time"
+)
+
+// DeprecationMiddleware marks the responses of deprecated routes with the Deprecation header
+// and, if the sunset time is known, the Sunset header (RFC 8594), e.g.:
+//
+//	Deprecation: true
+//	Sunset: Wed, 01 Jan 2025 00:00:00 GMT
+//
+// so that clients learn about the deprecation before the route is removed.
+// The matcher reports whether the request targets a deprecated route and when it is going away;
+// a zero sunset time means the date is not decided yet, and only the Deprecation header is sent.
+// The headers are added before the handler runs, so they are sent with error responses as well.
+func DeprecationMiddleware(matcher func(r *http.Request) (sunset time.Time, ok bool)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if sunset, ok := matcher(r); ok {
+				w.Header().Set("Deprecation", "true")
+				if !sunset.IsZero() {
+					w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package httpserver_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeprecationMiddleware(t *testing.T) {
+	sunset := time.Date(2025, 1, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+	handler := httpserver.DeprecationMiddleware(func(r *http.Request) (time.Time, bool) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v1/"):
+			return sunset, true
+		case strings.HasPrefix(r.URL.Path, "/v2/legacy"):
+			return time.Time{}, true
+		}
+		return time.Time{}, false
+	})(http.NotFoundHandler())
+
+	tests := []struct {
+		path        string
+		deprecation string
+		sunset      string
+	}{
+		{path: "/v1/users", deprecation: "true", sunset: "Wed, 01 Jan 2025 11:00:00 GMT"},
+		{path: "/v2/legacy", deprecation: "true"},
+		{path: "/v2/users"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			require.Equal(t, tt.deprecation, rec.Header().Get("Deprecation"))
+			require.Equal(t, tt.sunset, rec.Header().Get("Sunset"))
+			if tt.sunset != "" {
+				_, err := http.ParseTime(rec.Header().Get("Sunset"))
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package httpserver
+
+import "net/http"
+
+// drainMiddleware adds the "Connection: close" header to HTTP/1.x responses written once the server starts
+// shutting down, e.g. during WithShutdownDelay, so that clients stop reusing keep-alive connections
+// for new requests before the connections are closed. The shutdown flag is checked when the header is written,
+// so requests which started before the shutdown and respond during the delay are covered too.
+// It is only installed with a shutdown delay: without one, net/http itself closes the connections
+// after the response once the shutdown begins.
+func (s *Server) drainMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// HTTP/2 and later use GOAWAY frames instead
+		if r.ProtoMajor != 1 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		closeIfShuttingDown := func(int) {
+			if s.ShuttingDown() {
+				w.Header().Set("Connection", "close")
+			}
+		}
+
+		rw := newResponseWriter(w)
+		rw.beforeWriteHeader = closeIfShuttingDown
+		next.ServeHTTP(rw, r)
+
+		// The handler returned without writing, the server writes the header after the handler returns
+		if !rw.wroteHeader {
+			closeIfShuttingDown(http.StatusOK)
+		}
+	})
+}
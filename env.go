@@ -0,0 +1,66 @@
+package httpserver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultAddr is the address RunFromEnv listens on if neither ADDR nor PORT is set.
+const DefaultAddr = ":8080"
+
+// AddrFromEnv returns the listen address from the given environment variable, e.g. "PORT",
+// or the fallback if the variable is unset or empty.
+// The value may be a bare port, e.g. "8080", which means all interfaces (":8080"),
+// or a full address, e.g. "127.0.0.1:8080" or "[::1]:8080".
+// It returns ErrInvalidAddress if the resulting address is not a valid host and numeric port pair.
+func AddrFromEnv(envVar, fallback string) (string, error) {
+	addr := strings.TrimSpace(os.Getenv(envVar))
+	if addr == "" {
+		addr = fallback
+	} else if _, err := strconv.Atoi(addr); err == nil {
+		addr = ":" + addr
+	}
+
+	if err := validateAddr(addr); err != nil {
+		return "", fmt.Errorf("%w %q from %s: %v", ErrInvalidAddress, addr, envVar, err)
+	}
+	return addr, nil
+}
+
+// RunFromEnv starts an HTTP server like Run, taking the address from the environment,
+// as set by platforms like Heroku or Cloud Run.
+// The precedence is:
+//   - ADDR, a full address or a bare port, e.g. "127.0.0.1:8080";
+//   - PORT, a bare port, e.g. "8080", listening on all interfaces;
+//   - DefaultAddr.
+//
+// It returns ErrInvalidAddress without starting the server if the variable holds an invalid value.
+func RunFromEnv(ctx context.Context, handler http.Handler) error {
+	envVar := "ADDR"
+	if strings.TrimSpace(os.Getenv(envVar)) == "" {
+		envVar = "PORT"
+	}
+	addr, err := AddrFromEnv(envVar, DefaultAddr)
+	if err != nil {
+		return err
+	}
+	return Run(ctx, addr, handler)
+}
+
+// validateAddr checks that addr is a host and port pair with a port number in the valid range.
+func validateAddr(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 0 || n > 65535 {
+		return fmt.Errorf("port %q must be a number from 0 to 65535", port)
+	}
+	return nil
+}
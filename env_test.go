@@ -0,0 +1,59 @@
+package httpserver_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddrFromEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+		err      bool
+	}{
+		{name: "unset", value: "", expected: ":8080"},
+		{name: "bare port", value: "3000", expected: ":3000"},
+		{name: "host and port", value: "127.0.0.1:3000", expected: "127.0.0.1:3000"},
+		{name: "ipv6", value: "[::1]:3000", expected: "[::1]:3000"},
+		{name: "spaces", value: " 3000 ", expected: ":3000"},
+		{name: "port out of range", value: "70000", err: true},
+		{name: "named port", value: ":http", err: true},
+		{name: "missing port", value: "localhost", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HTTPSERVER_TEST_PORT", tt.value)
+
+			addr, err := httpserver.AddrFromEnv("HTTPSERVER_TEST_PORT", ":8080")
+			if tt.err {
+				require.ErrorIs(t, err, httpserver.ErrInvalidAddress)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, addr)
+		})
+	}
+}
+
+func TestRunFromEnv(t *testing.T) {
+	t.Run("invalid port", func(t *testing.T) {
+		t.Setenv("ADDR", "")
+		t.Setenv("PORT", "not-a-port")
+		err := httpserver.RunFromEnv(context.Background(), http.NotFoundHandler())
+		require.ErrorIs(t, err, httpserver.ErrInvalidAddress)
+	})
+
+	t.Run("addr takes precedence over port", func(t *testing.T) {
+		t.Setenv("PORT", "not-a-port")
+		t.Setenv("ADDR", "localhost:bad")
+		err := httpserver.RunFromEnv(context.Background(), http.NotFoundHandler())
+		require.ErrorIs(t, err, httpserver.ErrInvalidAddress)
+		require.Contains(t, err.Error(), "ADDR")
+	})
+}
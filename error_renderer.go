@@ -0,0 +1,47 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ErrorRenderer writes an error response with the given status code, e.g. in the format of an API.
+// The error describes the cause; for generic errors its message is the status text, e.g. "Not Found".
+type ErrorRenderer func(w http.ResponseWriter, r *http.Request, status int, err error)
+
+// JSONErrorRenderer is an ErrorRenderer which writes the error as a JSON object, e.g.:
+//
+//	{"error":"Not Found","status":404}
+func JSONErrorRenderer(w http.ResponseWriter, r *http.Request, status int, err error) {
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(struct {
+		Error  string `json:"error"`
+		Status int    `json:"status"`
+	}{
+		Error:  err.Error(),
+		Status: status,
+	})
+}
+
+// renderError writes an error response generated by the package, e.g. 404 Not Found of the static handlers,
+// with the ErrorRenderer of the server handling the request, if set with WithErrorRenderer, or as plain text.
+func renderError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	if s, ok := ServerFromContext(r.Context()); ok && s.errorRenderer != nil {
+		s.errorRenderer(w, r, status, err)
+		return
+	}
+	if status == http.StatusNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// statusError returns an error with the status text, for error responses without a more specific cause.
+func statusError(status int) error {
+	return errors.New(http.StatusText(status))
+}
@@ -0,0 +1,78 @@
+package httpserver_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorRenderer(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	static := httpserver.StaticHandler("/static", http.FS(fstest.MapFS{
+		"app.js": {Data: []byte("console.log('app')")},
+	}), 0)
+	server, err := httpserver.New(ln.Addr().String(), static,
+		httpserver.WithListener(ln),
+		httpserver.WithMiddleware(httpserver.RequireMaxContentLength(4)),
+		httpserver.WithErrorRenderer(httpserver.JSONErrorRenderer),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = server.Start(ctx) }()
+
+	url := "http://" + ln.Addr().String()
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		status  int
+		message string
+	}{
+		{name: "not found", method: http.MethodGet, path: "/static/missing.js", status: http.StatusNotFound, message: "Not Found"},
+		{name: "method not allowed", method: http.MethodDelete, path: "/static/app.js", status: http.StatusMethodNotAllowed, message: "Method Not Allowed"},
+		{name: "too large", method: http.MethodPost, path: "/static/app.js", body: "too large", status: http.StatusRequestEntityTooLarge, message: "Request Entity Too Large"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, url+tt.path, strings.NewReader(tt.body))
+			require.NoError(t, err)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			require.Equal(t, tt.status, resp.StatusCode)
+			require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+			var body struct {
+				Error  string `json:"error"`
+				Status int    `json:"status"`
+			}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			require.Equal(t, tt.message, body.Error)
+			require.Equal(t, tt.status, body.Status)
+		})
+	}
+
+	t.Run("successful response", func(t *testing.T) {
+		resp, err := http.Get(url + "/static/app.js")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "console.log('app')", string(body))
+	})
+}
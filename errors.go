@@ -4,8 +4,30 @@ import "errors"
 
 var (
 	ErrEmptyAddress     = errors.New("server address cannot be empty")
+	ErrInvalidAddress   = errors.New("invalid server address")
 	ErrNilHandler       = errors.New("server handler cannot be nil")
 	ErrServerStart      = errors.New("server failed to start")
 	ErrServerStop       = errors.New("server failed to stop")
 	ErrServerForceClose = errors.New("server force close failed")
+	ErrServerUnhealthy  = errors.New("server stopped due to a failing health check")
+	ErrTLSConfigIgnored = errors.New("server has TLS certificates configured but is started without TLS")
+	ErrNoTLSCertificate = errors.New("server is started with TLS but no certificate is configured")
+	ErrResponseAborted  = errors.New("response aborted because the server is shutting down")
+
+	ErrInvalidShutdownPhases = errors.New("graceful shutdown fraction must be greater than 0 and at most 1")
+	ErrListenConfigConflict  = errors.New("listen config cannot be combined with an existing listener")
+
+	ErrDecompressedBodyTooLarge = errors.New("decompressed request body is too large")
+
+	ErrInvalidTLSCertificate = errors.New("invalid TLS certificate or key")
+
+	ErrInvalidCompressionLevel   = errors.New("invalid compression level")
+	ErrInvalidCompressionMinSize = errors.New("invalid compression minimum size")
+	ErrInvalidContentType        = errors.New("invalid content type")
+
+	ErrInvalidStaticFS   = errors.New("static file system cannot be read")
+	ErrEmptyStaticFS     = errors.New("static file system is empty")
+	ErrMissingStaticFile = errors.New("required static file is missing")
+
+	ErrInvalidIntegrityManifest = errors.New("invalid integrity manifest")
 )
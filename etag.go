@@ -0,0 +1,47 @@
+package httpserver
+
+import "net/http"
+
+// etagBufferLimit is the maximum size of a response body buffered by ETagMiddleware.
+// Larger responses are sent as they are written, without an ETag.
+const etagBufferLimit = 1 << 20 // 1 MB
+
+// ETagMiddleware adds an ETag computed from the response body to successful responses of GET requests,
+// e.g. of dynamic JSON endpoints, and answers conditional requests whose If-None-Match header matches it
+// with 304 Not Modified and no body, so that clients can revalidate their cached copy cheaply.
+// The handler still runs on every request; only the transfer of an unchanged body is saved.
+//
+// The response is buffered to compute the hash. Responses larger than 1 MB, flushed responses,
+// e.g. streams, and hijacked connections are sent as they are written, without an ETag.
+// The ETag is weak, since the same body may be sent with different content codings, e.g. gzip.
+// If the handler sets an ETag itself, it is kept and used for the conditional request.
+func ETagMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rw := newBufferedWriter(w, w.Header().Clone(), etagBufferLimit)
+			next.ServeHTTP(rw, r)
+			if rw.committed || rw.status != http.StatusOK {
+				rw.commit()
+				return
+			}
+
+			etag := rw.header.Get("ETag")
+			if etag == "" {
+				etag = "W/" + contentETag(rw.buf.Bytes())
+				rw.header.Set("ETag", etag)
+			}
+			if etagMatches(r.Header.Get("If-None-Match"), etag) {
+				rw.header.Del("Content-Type")
+				rw.header.Del("Content-Length")
+				rw.status = http.StatusNotModified
+				rw.buf.Reset()
+			}
+			rw.commit()
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package httpserver_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestETagMiddleware(t *testing.T) {
+	var calls atomic.Int32
+	body := `{"id":1,"name":"Alice"}`
+	handler := httpserver.ETagMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		switch r.URL.Path {
+		case "/stream":
+			_, _ = w.Write([]byte("chunk"))
+			http.NewResponseController(w).Flush()
+		case "/large":
+			_, _ = w.Write([]byte(strings.Repeat("a", 2<<20)))
+		case "/error":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(body))
+		}
+	}))
+
+	get := func(path, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/user", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, body, rec.Body.String())
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	etag := rec.Header().Get("ETag")
+	require.True(t, strings.HasPrefix(etag, `W/"`), etag)
+
+	// The same body has the same ETag, and a matching conditional request gets 304 without the body
+	rec = get("/user", etag)
+	require.Equal(t, http.StatusNotModified, rec.Code)
+	require.Empty(t, rec.Body.String())
+	require.Equal(t, etag, rec.Header().Get("ETag"))
+	require.EqualValues(t, 2, calls.Load())
+
+	rec = get("/user", `W/"outdated"`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, body, rec.Body.String())
+
+	t.Run("not tagged", func(t *testing.T) {
+		for _, path := range []string{"/stream", "/large", "/error"} {
+			rec := get(path, "*")
+			require.NotEqual(t, http.StatusNotModified, rec.Code, path)
+			require.Empty(t, rec.Header().Get("ETag"), path)
+			require.NotEmpty(t, rec.Body.String(), path)
+		}
+	})
+
+	t.Run("non-GET request", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/user", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Empty(t, rec.Header().Get("ETag"))
+	})
+}
+
+func TestETagMiddlewareEarlyHints(t *testing.T) {
+	handler := httpserver.ETagMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</app.css>; rel=preload; as=style")
+		w.WriteHeader(http.StatusEarlyHints)
+		_, _ = w.Write([]byte("page"))
+	}))
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	var (
+		hints []int
+		links []string
+	)
+	req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			hints = append(hints, code)
+			links = append(links, header.Get("Link"))
+			return nil
+		},
+	}))
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, []int{http.StatusEarlyHints}, hints)
+	require.Equal(t, []string{"</app.css>; rel=preload; as=style"}, links)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NotEmpty(t, resp.Header.Get("ETag"))
+}
@@ -0,0 +1,59 @@
+package httpserver
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// defaultFaviconCacheTTL is the cache TTL of the favicon used when a non-positive one is given to FaviconHandler.
+const defaultFaviconCacheTTL = 7 * 24 * time.Hour
+
+// transparentFavicon is a 1x1 transparent icon in the ICO format, served by FaviconHandler when no icon is given.
+var transparentFavicon = []byte{
+	// ICONDIR: reserved, type 1 (icon), 1 image
+	0x00, 0x00, 0x01, 0x00, 0x01, 0x00,
+	// ICONDIRENTRY: 1x1, no palette, 1 plane, 32 bits per pixel, 48 bytes of image data at offset 22
+	0x01, 0x01, 0x00, 0x00, 0x01, 0x00, 0x20, 0x00, 0x30, 0x00, 0x00, 0x00, 0x16, 0x00, 0x00, 0x00,
+	// BITMAPINFOHEADER: 40 bytes, 1x2 (the XOR and AND masks), 1 plane, 32 bits per pixel, uncompressed
+	0x28, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x01, 0x00, 0x20, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	// XOR mask: a single fully transparent BGRA pixel
+	0x00, 0x00, 0x00, 0x00,
+	// AND mask: a single row padded to 4 bytes
+	0x00, 0x00, 0x00, 0x00,
+}
+
+// FaviconHandler returns an http.HandlerFunc serving the given icon, so that the requests browsers send
+// for "/favicon.ico" on their own do not end up as 404 noise in the logs, e.g.:
+//
+//	mux.Handle("/favicon.ico", httpserver.FaviconHandler(nil, 0))
+//
+// If data is empty, a transparent 1x1 icon is served. The icon is sent as "image/x-icon" with an ETag,
+// so revalidations get 304 Not Modified, and cached for the given TTL, or for 7 days if it is not positive.
+// Methods other than GET and HEAD get 405 Method Not Allowed.
+func FaviconHandler(data []byte, cacheTTL time.Duration) http.HandlerFunc {
+	if len(data) == 0 {
+		data = transparentFavicon
+	}
+	if cacheTTL <= 0 {
+		cacheTTL = defaultFaviconCacheTTL
+	}
+	etag := contentETag(data)
+	cacheControl := fmt.Sprintf("public, max-age=%d", int(cacheTTL.Seconds()))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			renderError(w, r, http.StatusMethodNotAllowed, statusError(http.StatusMethodNotAllowed))
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/x-icon")
+		w.Header().Set("Cache-Control", cacheControl)
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "favicon.ico", time.Time{}, bytes.NewReader(data))
+	}
+}
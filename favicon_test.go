@@ -0,0 +1,55 @@
+package httpserver_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFaviconHandler(t *testing.T) {
+	t.Run("default icon", func(t *testing.T) {
+		handler := httpserver.FaviconHandler(nil, 0)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/favicon.ico", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "image/x-icon", rec.Header().Get("Content-Type"))
+		require.Equal(t, "public, max-age=604800", rec.Header().Get("Cache-Control"))
+		require.NotEmpty(t, rec.Header().Get("ETag"))
+		// ICO header: reserved, type 1, one image of 1x1
+		body := rec.Body.Bytes()
+		require.Equal(t, []byte{0, 0, 1, 0, 1, 0, 1, 1}, body[:8])
+		require.Equal(t, "70", rec.Header().Get("Content-Length"))
+
+		// Revalidation with the ETag gets 304
+		req := httptest.NewRequest(http.MethodGet, "/favicon.ico", nil)
+		req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusNotModified, rec.Code)
+		require.Empty(t, rec.Body.Bytes())
+	})
+
+	t.Run("custom icon", func(t *testing.T) {
+		icon := []byte("custom icon bytes")
+		handler := httpserver.FaviconHandler(icon, 24*time.Hour)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/favicon.ico", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "image/x-icon", rec.Header().Get("Content-Type"))
+		require.Equal(t, "public, max-age=86400", rec.Header().Get("Cache-Control"))
+		require.Equal(t, icon, rec.Body.Bytes())
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		httpserver.FaviconHandler(nil, 0).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/favicon.ico", nil))
+		require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+		require.Equal(t, "GET, HEAD", rec.Header().Get("Allow"))
+	})
+}
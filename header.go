@@ -0,0 +1,143 @@
+package httpserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// qualityValue is a single entry of a header with q-values, e.g. Accept or Accept-Encoding.
+type qualityValue struct {
+	value string
+	q     float64
+}
+
+// parseQualityList parses a comma-separated header value with optional q-values,
+// e.g. "gzip;q=0.8, br" or "image/webp, */*;q=0.8".
+// Values are lower-cased and stripped of parameters other than q.
+// Entries with a malformed q-value are skipped.
+func parseQualityList(header string) []qualityValue {
+	var result []qualityValue
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		value, params, _ := strings.Cut(part, ";")
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+
+		q, ok := parseQValue(params)
+		if !ok {
+			continue
+		}
+		result = append(result, qualityValue{value: value, q: q})
+	}
+	return result
+}
+
+// parseQValue extracts the q parameter from the parameters part of a header entry.
+// It returns 1 if no q parameter is present and false if the value is malformed.
+func parseQValue(params string) (float64, bool) {
+	q := 1.0
+	for _, param := range strings.Split(params, ";") {
+		key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+		if !found || !strings.EqualFold(strings.TrimSpace(key), "q") {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || v < 0 || v > 1 {
+			return 0, false
+		}
+		q = v
+	}
+	return q, true
+}
+
+// acceptsMediaType reports whether the Accept header value explicitly accepts the given media type with a non-zero quality.
+// Wildcards are not taken into account, since clients usually send "*/*" regardless of the actual support.
+func acceptsMediaType(header, mediaType string) bool {
+	for _, v := range parseQualityList(header) {
+		if v.value == mediaType {
+			return v.q > 0
+		}
+	}
+	return false
+}
+
+// etagMatches reports whether the If-None-Match header value matches the ETag.
+// The header is a comma-separated list of entity tags or "*", which matches any ETag.
+// Entity tags are compared with the weak comparison function of RFC 7232,
+// so "W/" prefixes are ignored on both sides.
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for header != "" {
+		header = strings.TrimLeft(header, " \t,")
+		if header == "" {
+			break
+		}
+		if header[0] == '*' {
+			// Only a bare "*" entry matches any ETag, anything else is malformed
+			rest := strings.TrimLeft(header[1:], " \t")
+			return rest == "" || rest[0] == ','
+		}
+
+		tag, rest, ok := scanETag(header)
+		if !ok {
+			// Malformed list, nothing can match
+			return false
+		}
+		if strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+		header = rest
+	}
+	return false
+}
+
+// contentETag returns a strong entity tag derived from the SHA-256 hash of the content.
+func contentETag(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hashETag(sum[:])
+}
+
+// readerETag is like contentETag, but hashes the content of the reader
+// and rewinds it, so that the content can be served afterwards.
+func readerETag(content io.ReadSeeker) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hashETag(h.Sum(nil)), nil
+}
+
+// hashETag formats the first 128 bits of the hash sum as an entity tag.
+func hashETag(sum []byte) string {
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// scanETag reads an entity tag, weak or strong, from the beginning of the string
+// and returns it along with the remainder of the string.
+func scanETag(s string) (string, string, bool) {
+	start := 0
+	if strings.HasPrefix(s, "W/") {
+		start = 2
+	}
+	if len(s) < start+2 || s[start] != '"' {
+		return "", "", false
+	}
+	end := strings.IndexByte(s[start+1:], '"')
+	if end < 0 {
+		return "", "", false
+	}
+	end += start + 2
+	return s[:end], s[end:], true
+}
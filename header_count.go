@@ -0,0 +1,47 @@
+package httpserver
+
+import "net/http"
+
+// MaxHeaderCountMiddleware rejects requests with more than n header fields with 431 Request Header Fields Too Large.
+// Every field line is counted, including repeated names; the Host header is not, since net/http moves it to r.Host.
+// This complements the server MaxHeaderBytes limit, which caps the total size but lets through
+// a flood of small fields, each costing a map entry. A non-positive n disables the limit.
+func MaxHeaderCountMiddleware(n int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if n <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var count int
+			for _, values := range r.Header {
+				count += len(values)
+			}
+			if count > n {
+				renderError(w, r, http.StatusRequestHeaderFieldsTooLarge, statusError(http.StatusRequestHeaderFieldsTooLarge))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// maxHeaderValueLengthMiddleware rejects requests with a header value longer than n bytes
+// with 431 Request Header Fields Too Large. It is enabled with WithMaxHeaderValueLength.
+func (s *Server) maxHeaderValueLengthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, values := range r.Header {
+			for _, v := range values {
+				if len(v) > s.maxHeaderValueLen {
+					s.log.InfoContext(r.Context(), "rejected request with an oversized header value",
+						"header", name,
+						"length", len(v),
+						"limit", s.maxHeaderValueLen,
+					)
+					renderError(w, r, http.StatusRequestHeaderFieldsTooLarge, statusError(http.StatusRequestHeaderFieldsTooLarge))
+					return
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
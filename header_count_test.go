@@ -0,0 +1,105 @@
+package httpserver_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxHeaderCountMiddleware(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	handler := httpserver.MaxHeaderCountMiddleware(20)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv := &http.Server{Handler: handler}
+	go func() { _ = srv.Serve(ln) }()
+	defer srv.Close()
+
+	// The client adds User-Agent and Accept-Encoding to the custom fields
+	tests := []struct {
+		name     string
+		fields   int
+		repeated bool
+		expected int
+	}{
+		{name: "within the limit", fields: 18, expected: http.StatusOK},
+		{name: "one over the limit", fields: 19, expected: http.StatusRequestHeaderFieldsTooLarge},
+		{name: "excessive fields", fields: 100, expected: http.StatusRequestHeaderFieldsTooLarge},
+		{name: "excessive repeated fields", fields: 100, repeated: true, expected: http.StatusRequestHeaderFieldsTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "http://"+ln.Addr().String(), nil)
+			require.NoError(t, err)
+			for i := 0; i < tt.fields; i++ {
+				if tt.repeated {
+					req.Header.Add("X-Field", fmt.Sprint(i))
+				} else {
+					req.Header.Set(fmt.Sprintf("X-Field-%d", i), "1")
+				}
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, tt.expected, resp.StatusCode)
+		})
+	}
+}
+
+func TestMaxHeaderValueLength(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	log := &httpserver.MemoryLogger{}
+	server, err := httpserver.New(ln.Addr().String(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		httpserver.WithListener(ln),
+		httpserver.WithLogger(log),
+		httpserver.WithMaxHeaderBytes(64<<10),
+		httpserver.WithMaxHeaderValueLength(4<<10),
+	)
+	require.NoError(t, err)
+	require.Equal(t, 4<<10, server.Config().MaxHeaderValueLength)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- server.Start(ctx) }()
+	defer func() {
+		cancel()
+		require.NoError(t, <-stopped)
+	}()
+
+	get := func(name, value string) int {
+		req, err := http.NewRequest(http.MethodGet, "http://"+ln.Addr().String(), nil)
+		require.NoError(t, err)
+		req.Header.Add(name, value)
+		req.Header.Add(name, "small")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err == nil {
+			conn.Close()
+		}
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	// A value at the limit is accepted
+	require.Equal(t, http.StatusOK, get("Cookie", strings.Repeat("a", 4<<10)))
+
+	// A single huge value is rejected, although the headers are well under the total budget
+	require.Equal(t, http.StatusRequestHeaderFieldsTooLarge, get("Cookie", strings.Repeat("a", 16<<10)))
+	rejected := log.Find("rejected request with an oversized header value")
+	require.Len(t, rejected, 1)
+	header, _ := rejected[0].Field("header")
+	require.Equal(t, "Cookie", header)
+}
@@ -0,0 +1,160 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HealthCheckFunc checks a dependency of the server, e.g. a database connection.
+// It returns an error if the dependency is unhealthy.
+type HealthCheckFunc func(ctx context.Context) error
+
+// healthCheck is a registered health check with its latest result.
+type healthCheck struct {
+	name  string
+	check HealthCheckFunc
+
+	mu           sync.Mutex
+	lastErr      error
+	failingSince time.Time
+}
+
+// run executes the check and records the result.
+func (c *healthCheck) run(ctx context.Context) error {
+	err := c.check(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastErr = err
+	switch {
+	case err == nil:
+		c.failingSince = time.Time{}
+	case c.failingSince.IsZero():
+		c.failingSince = time.Now()
+	}
+	return err
+}
+
+// failingFor returns for how long the check has been failing continuously, or zero if it is healthy.
+func (c *healthCheck) failingFor() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failingSince.IsZero() {
+		return 0
+	}
+	return time.Since(c.failingSince)
+}
+
+// healthResponse is the JSON body of the health handler.
+type healthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// HealthHandler returns an http.HandlerFunc which runs all the health checks registered with WithHealthCheck
+// and responds with their results as JSON: 200 OK if all of them pass, 503 Service Unavailable otherwise.
+// The checks run concurrently and inherit the request context.
+func (s *Server) HealthHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := healthResponse{Status: "ok", Checks: make(map[string]string, len(s.healthChecks))}
+
+		var (
+			mu sync.Mutex
+			wg sync.WaitGroup
+		)
+		for _, c := range s.healthChecks {
+			c := c
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				result := "ok"
+				if err := c.run(r.Context()); err != nil {
+					result = err.Error()
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				resp.Checks[c.name] = result
+				if result != "ok" {
+					resp.Status = "fail"
+				}
+			}()
+		}
+		wg.Wait()
+
+		status := http.StatusOK
+		if resp.Status != "ok" {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// healthCheckByName returns the registered health check with the given name.
+func (s *Server) healthCheckByName(name string) (*healthCheck, bool) {
+	for _, c := range s.healthChecks {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// healthCheckNames returns the names of the registered health checks in alphabetical order.
+func (s *Server) healthCheckNames() []string {
+	names := make([]string, 0, len(s.healthChecks))
+	for _, c := range s.healthChecks {
+		names = append(names, c.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// monitorHealth runs the named health check periodically while the context is alive
+// and calls shutdown once the check has been failing continuously for the given duration.
+func (s *Server) monitorHealth(ctx context.Context, name string, after time.Duration, shutdown func()) {
+	c, ok := s.healthCheckByName(name)
+	if !ok {
+		s.log.ErrorContext(ctx, "unknown health check, shutdown on unhealthy is disabled",
+			"check", name,
+			"registered", s.healthCheckNames(),
+		)
+		return
+	}
+
+	interval := after / 4
+	if interval < 100*time.Millisecond {
+		interval = 100 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			checkCtx, cancel := context.WithTimeout(ctx, interval)
+			err := c.run(checkCtx)
+			cancel()
+
+			if failing := c.failingFor(); err != nil && failing >= after {
+				s.log.ErrorContext(ctx, "health check has been failing too long, initiating shutdown",
+					"check", name,
+					"failing_for", failing,
+					"error", err,
+				)
+				shutdown()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// HostValidationMiddleware rejects requests whose host is not in the allowlist with 400 Bad Request,
+// to defend against host header injection and cache poisoning, e.g. when absolute URLs or redirects
+// are generated from the request host.
+//
+// The allowlist entries are host names without a port. An entry like "*.example.com" matches
+// any subdomain of example.com, but not example.com itself. Matching is case-insensitive.
+// The X-Forwarded-Host header is used instead of the Host header only if the immediate peer
+// belongs to one of the trusted proxy prefixes; otherwise it is ignored, since anyone can send it.
+func HostValidationMiddleware(allowed []string, trustedProxies ...netip.Prefix) func(http.Handler) http.Handler {
+	exact := make(map[string]bool, len(allowed))
+	var suffixes []string
+	for _, h := range allowed {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if suffix, ok := strings.CutPrefix(h, "*"); ok {
+			suffixes = append(suffixes, suffix)
+			continue
+		}
+		exact[h] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host := requestHost(r, trustedProxies)
+			if !exact[host] && !hasAllowedSuffix(host, suffixes) {
+				renderError(w, r, http.StatusBadRequest, statusError(http.StatusBadRequest))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// hasAllowedSuffix reports whether the host is a subdomain matching any of the wildcard suffixes, e.g. ".example.com".
+func hasAllowedSuffix(host string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if len(host) > len(suffix) && strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
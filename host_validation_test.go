@@ -0,0 +1,72 @@
+package httpserver_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostValidationMiddleware(t *testing.T) {
+	handler := httpserver.HostValidationMiddleware(
+		[]string{"example.com", "*.example.org"},
+		netip.MustParsePrefix("10.0.0.0/8"),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name          string
+		host          string
+		forwardedHost string
+		remoteAddr    string
+		expected      int
+	}{
+		{name: "allowed host", host: "example.com", expected: http.StatusOK},
+		{name: "allowed host with port", host: "EXAMPLE.com:8080", expected: http.StatusOK},
+		{name: "wildcard subdomain", host: "api.example.org", expected: http.StatusOK},
+		{name: "nested wildcard subdomain", host: "v1.api.example.org", expected: http.StatusOK},
+		{name: "wildcard apex", host: "example.org", expected: http.StatusBadRequest},
+		{name: "spoofed host", host: "evil.com", expected: http.StatusBadRequest},
+		{name: "suffix lookalike", host: "evilexample.org", expected: http.StatusBadRequest},
+		{name: "subdomain of exact host", host: "www.example.com", expected: http.StatusBadRequest},
+		{
+			name:          "forwarded host from trusted proxy",
+			host:          "internal.local",
+			forwardedHost: "example.com",
+			remoteAddr:    "10.1.2.3:1234",
+			expected:      http.StatusOK,
+		},
+		{
+			name:          "spoofed forwarded host from trusted proxy",
+			host:          "example.com",
+			forwardedHost: "evil.com",
+			remoteAddr:    "10.1.2.3:1234",
+			expected:      http.StatusBadRequest,
+		},
+		{
+			name:          "forwarded host from untrusted peer is ignored",
+			host:          "evil.com",
+			forwardedHost: "example.com",
+			remoteAddr:    "203.0.113.1:1234",
+			expected:      http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Host = tt.host
+			if tt.forwardedHost != "" {
+				req.Header.Set("X-Forwarded-Host", tt.forwardedHost)
+			}
+			if tt.remoteAddr != "" {
+				req.RemoteAddr = tt.remoteAddr
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			require.Equal(t, tt.expected, rec.Code)
+		})
+	}
+}
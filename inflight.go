@@ -0,0 +1,114 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxTrackedRequests bounds the number of in-flight requests kept by the tracker.
+// Requests beyond this limit are only counted.
+const maxTrackedRequests = 1000
+
+// inflightRequest describes a request which is being handled.
+type inflightRequest struct {
+	method  string
+	path    string
+	started time.Time
+}
+
+// inflightTracker keeps track of the requests which are being handled by the server.
+// It is used to report requests which failed to complete within the shutdown timeout.
+type inflightTracker struct {
+	mu        sync.Mutex
+	nextID    uint64
+	requests  map[uint64]inflightRequest
+	untracked int
+}
+
+// newInflightTracker creates a new in-flight request tracker.
+func newInflightTracker() *inflightTracker {
+	return &inflightTracker{
+		requests: make(map[uint64]inflightRequest),
+	}
+}
+
+// add registers the request and returns a function which must be called when the request is completed.
+func (t *inflightTracker) add(r *http.Request) func() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.requests) >= maxTrackedRequests {
+		t.untracked++
+		return func() {
+			t.mu.Lock()
+			t.untracked--
+			t.mu.Unlock()
+		}
+	}
+
+	t.nextID++
+	id := t.nextID
+	t.requests[id] = inflightRequest{
+		method:  r.Method,
+		path:    r.URL.Path,
+		started: time.Now(),
+	}
+	return func() {
+		t.mu.Lock()
+		delete(t.requests, id)
+		t.mu.Unlock()
+	}
+}
+
+// snapshot returns the tracked requests ordered by start time and the number of untracked ones.
+func (t *inflightTracker) snapshot() ([]inflightRequest, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := make([]inflightRequest, 0, len(t.requests))
+	for _, r := range t.requests {
+		result = append(result, r)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].started.Before(result[j].started)
+	})
+	return result, t.untracked
+}
+
+// middleware tracks every request passing through the handler.
+func (t *inflightTracker) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		done := t.add(r)
+		defer done()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// logInflightRequests logs the requests which are still being handled.
+// It is called when the graceful shutdown times out.
+func (s *Server) logInflightRequests(ctx context.Context) {
+	if s.inflight == nil {
+		return
+	}
+
+	requests, untracked := s.inflight.snapshot()
+	if len(requests) == 0 && untracked == 0 {
+		return
+	}
+
+	s.log.ErrorContext(ctx, "requests did not complete within the shutdown timeout",
+		"count", len(requests)+untracked,
+		"untracked", untracked,
+	)
+	for _, r := range requests {
+		s.log.ErrorContext(ctx, "incomplete request",
+			"method", r.method,
+			"path", r.path,
+			"started_at", r.started,
+			"duration", time.Since(r.started),
+		)
+	}
+}
@@ -0,0 +1,15 @@
+package httpserver
+
+import "net/http"
+
+// Instrumentation observes the server requests and lifecycle, e.g. to trace them.
+// The OpenTelemetry implementation lives in the otelserver subpackage
+// to keep its dependencies out of the core package.
+type Instrumentation interface {
+	// Handler wraps the server handler. It is applied last, so it wraps all the server middlewares
+	// and observes every response, including the ones written by the server itself.
+	Handler(next http.Handler) http.Handler
+	// Logger wraps the server logger, which receives the lifecycle events, e.g. server start and stop,
+	// with the context passed to Start.
+	Logger(log Logger) Logger
+}
@@ -0,0 +1,154 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Bounds of the delay between retries of a failed Accept call.
+const (
+	minAcceptRetryDelay = 5 * time.Millisecond
+	maxAcceptRetryDelay = 1 * time.Second
+)
+
+// additionalListener describes an extra address the server listens on.
+type additionalListener struct {
+	addr      string
+	tlsConfig *tls.Config
+	handler   http.Handler
+}
+
+// handlerListener binds its own handler to the connections accepted by the wrapped listener.
+// The handler is passed to the requests via the base context of the listener.
+type handlerListener struct {
+	net.Listener
+	handler http.Handler
+}
+
+// listenerHandlerContextKey is the context key for the handler bound to the listener.
+type listenerHandlerContextKey struct{}
+
+// listenerHandlerMiddleware dispatches the requests accepted by the listeners with their own handler to it,
+// and all the other requests to next.
+func listenerHandlerMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h, ok := r.Context().Value(listenerHandlerContextKey{}).(http.Handler); ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// acceptErrorListener wraps a net.Listener and consults the handler when Accept fails.
+// If the handler reports the error as recoverable, Accept is retried with an exponential backoff
+// instead of returning the error to http.Server.Serve, which would stop the server.
+type acceptErrorListener struct {
+	net.Listener
+	ctx     context.Context
+	handler func(error) bool
+	log     Logger
+}
+
+// Accept waits for and returns the next connection to the listener.
+// A back-off is cut short by the cancellation of the server context, in which case http.ErrServerClosed is returned.
+func (l *acceptErrorListener) Accept() (net.Conn, error) {
+	var delay time.Duration
+	for {
+		conn, err := l.Listener.Accept()
+		if err == nil {
+			return conn, nil
+		}
+		if errors.Is(err, net.ErrClosed) {
+			return nil, err
+		}
+
+		if !l.handler(err) {
+			l.log.ErrorContext(l.ctx, "fatal accept error", "error", err)
+			return nil, err
+		}
+
+		if delay == 0 {
+			delay = minAcceptRetryDelay
+		} else {
+			delay *= 2
+		}
+		if delay > maxAcceptRetryDelay {
+			delay = maxAcceptRetryDelay
+		}
+		l.log.ErrorContext(l.ctx, "recoverable accept error, retrying",
+			"error", err,
+			"retry_in", delay,
+		)
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-l.ctx.Done():
+			t.Stop()
+			return nil, http.ErrServerClosed
+		}
+	}
+}
+
+// tcpOptionsListener wraps a net.Listener and sets the socket options configured with WithTCPNoDelay
+// and WithSocketBufferSizes on the accepted TCP connections.
+type tcpOptionsListener struct {
+	net.Listener
+	ctx         context.Context
+	log         Logger
+	noDelay     *bool
+	readBuffer  int
+	writeBuffer int
+	logOnce     sync.Once
+}
+
+// Accept waits for and returns the next connection to the listener, with the socket options set.
+func (l *tcpOptionsListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	nc := conn
+	if tc, ok := nc.(*tls.Conn); ok {
+		nc = tc.NetConn()
+	}
+	tcp, ok := nc.(*net.TCPConn)
+	if !ok {
+		return conn, nil
+	}
+
+	if l.noDelay != nil {
+		_ = tcp.SetNoDelay(*l.noDelay)
+	}
+	if l.readBuffer > 0 {
+		_ = tcp.SetReadBuffer(l.readBuffer)
+	}
+	if l.writeBuffer > 0 {
+		_ = tcp.SetWriteBuffer(l.writeBuffer)
+	}
+	if l.readBuffer > 0 || l.writeBuffer > 0 {
+		l.logOnce.Do(func() { l.logBufferSizes(tcp) })
+	}
+	return conn, nil
+}
+
+// logBufferSizes logs the socket buffer sizes in effect, which the OS may adjust, e.g. Linux doubles
+// the requested sizes and clamps them to the system limits. Nothing is logged if the sizes cannot be read.
+func (l *tcpOptionsListener) logBufferSizes(tcp *net.TCPConn) {
+	read, write, ok := socketBufferSizes(tcp)
+	if !ok {
+		return
+	}
+	l.log.InfoContext(l.ctx, "socket buffer sizes applied",
+		"addr", l.Addr().String(),
+		"read_buffer", l.readBuffer,
+		"effective_read_buffer", read,
+		"write_buffer", l.writeBuffer,
+		"effective_write_buffer", write,
+	)
+}
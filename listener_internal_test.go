@@ -0,0 +1,78 @@
+//go:build unix
+
+package httpserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTCPNoDelay(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		ln, err := net.Listen("tcp", "localhost:0")
+		require.NoError(t, err)
+		defer ln.Close()
+
+		s, err := New(ln.Addr().String(), http.NotFoundHandler(), WithTCPNoDelay(enabled))
+		require.NoError(t, err)
+		wrapped := s.wrapListener(context.Background(), ln)
+
+		client, err := net.Dial("tcp", ln.Addr().String())
+		require.NoError(t, err)
+		defer client.Close()
+		conn, err := wrapped.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		raw, err := conn.(*net.TCPConn).SyscallConn()
+		require.NoError(t, err)
+		var value int
+		var sockErr error
+		require.NoError(t, raw.Control(func(fd uintptr) {
+			value, sockErr = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_NODELAY)
+		}))
+		require.NoError(t, sockErr)
+		require.Equal(t, enabled, value != 0, "enabled=%v", enabled)
+	}
+}
+
+func TestSocketBufferSizes(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	log := &MemoryLogger{}
+	s, err := New(ln.Addr().String(), http.NotFoundHandler(),
+		WithLogger(log),
+		WithSocketBufferSizes(64<<10, 128<<10),
+	)
+	require.NoError(t, err)
+	wrapped := s.wrapListener(context.Background(), ln)
+
+	for i := 0; i < 2; i++ {
+		client, err := net.Dial("tcp", ln.Addr().String())
+		require.NoError(t, err)
+		defer client.Close()
+		conn, err := wrapped.Accept()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		// The OS may round the sizes up, e.g. Linux doubles them
+		read, write, ok := socketBufferSizes(conn.(*net.TCPConn))
+		require.True(t, ok)
+		require.GreaterOrEqual(t, read, 64<<10)
+		require.GreaterOrEqual(t, write, 128<<10)
+	}
+
+	// The effective sizes are logged once per listener
+	entries := log.Find("socket buffer sizes applied")
+	require.Len(t, entries, 1)
+	effective, ok := entries[0].Field("effective_read_buffer")
+	require.True(t, ok)
+	require.GreaterOrEqual(t, effective, 64<<10)
+}
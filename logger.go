@@ -0,0 +1,66 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"strings"
+)
+
+// fieldsLogger is a Logger which prepends a fixed set of fields to every log call.
+type fieldsLogger struct {
+	Logger
+	fields []interface{}
+}
+
+// InfoContext logs an info message with the fixed fields prepended.
+func (l *fieldsLogger) InfoContext(ctx context.Context, msg string, keyvals ...interface{}) {
+	l.Logger.InfoContext(ctx, msg, l.with(keyvals)...)
+}
+
+// ErrorContext logs an error message with the fixed fields prepended.
+func (l *fieldsLogger) ErrorContext(ctx context.Context, msg string, keyvals ...interface{}) {
+	l.Logger.ErrorContext(ctx, msg, l.with(keyvals)...)
+}
+
+// DebugContext logs a debug message with the fixed fields prepended, if the underlying logger supports debug messages.
+func (l *fieldsLogger) DebugContext(ctx context.Context, msg string, keyvals ...interface{}) {
+	if dl, ok := l.Logger.(debugLogger); ok {
+		dl.DebugContext(ctx, msg, l.with(keyvals)...)
+	}
+}
+
+// with returns the fixed fields followed by the given key-value pairs.
+func (l *fieldsLogger) with(keyvals []interface{}) []interface{} {
+	result := make([]interface{}, 0, len(l.fields)+len(keyvals))
+	result = append(result, l.fields...)
+	return append(result, keyvals...)
+}
+
+// debugLogger is implemented by loggers which support debug messages, e.g. *slog.Logger.
+// The Logger interface does not require it, so debug messages are dropped for other loggers.
+type debugLogger interface {
+	DebugContext(ctx context.Context, msg string, keyvals ...interface{})
+}
+
+// logDebug logs a debug message if the server logger supports debug messages.
+func (s *Server) logDebug(ctx context.Context, msg string, keyvals ...interface{}) {
+	if dl, ok := s.log.(debugLogger); ok {
+		dl.DebugContext(ctx, msg, keyvals...)
+	}
+}
+
+// logWriter adapts a Logger to io.Writer, so that it can back a *log.Logger.
+// Every write is logged as a separate error message, except the TLS handshake errors of net/http,
+// which the server already counts and logs with rate limiting, see observeTLSHandshake.
+type logWriter struct {
+	log Logger
+}
+
+// Write logs the message with the trailing newline trimmed.
+func (w *logWriter) Write(p []byte) (int, error) {
+	if bytes.HasPrefix(p, []byte("http: TLS handshake error")) {
+		return len(p), nil
+	}
+	w.log.ErrorContext(context.Background(), strings.TrimRight(string(p), "\n"), "source", "net/http")
+	return len(p), nil
+}
@@ -0,0 +1,113 @@
+package httpserver
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type maintenanceOption func(*maintenanceConfig)
+
+// maintenanceConfig holds the configuration of the maintenance middleware.
+type maintenanceConfig struct {
+	allowlist  []string
+	retryAfter time.Duration
+}
+
+// WithMaintenanceAllowlist sets the URL paths which are served as usual in maintenance mode, e.g. health checks.
+// A path ending with a slash matches all the paths under it, any other path must match exactly.
+func WithMaintenanceAllowlist(paths ...string) maintenanceOption {
+	return func(cfg *maintenanceConfig) {
+		cfg.allowlist = append(cfg.allowlist, paths...)
+	}
+}
+
+// WithMaintenanceRetryAfter sets the Retry-After header of the maintenance responses,
+// rounded up to whole seconds. By default the header is not sent.
+func WithMaintenanceRetryAfter(d time.Duration) maintenanceOption {
+	return func(cfg *maintenanceConfig) {
+		cfg.retryAfter = d
+	}
+}
+
+// allowed reports whether the URL path is served as usual in maintenance mode.
+func (cfg *maintenanceConfig) allowed(path string) bool {
+	for _, p := range cfg.allowlist {
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaintenanceMiddleware serves the maintenance page with 503 Service Unavailable for all requests
+// while the enabled flag is set, except the ones allowed with WithMaintenanceAllowlist.
+// The flag is checked on every request, so maintenance mode can be toggled at runtime without a restart.
+//
+// The page handler provides the response body and headers, e.g. a StaticHandler serving maintenance.html;
+// its status code is always replaced with 503, and the response is marked as not cacheable.
+// If the page is nil, a plain-text 503 response is sent.
+func MaintenanceMiddleware(enabled *atomic.Bool, page http.Handler, opts ...maintenanceOption) func(http.Handler) http.Handler {
+	cfg := &maintenanceConfig{}
+	for _, o := range opts {
+		o(cfg)
+	}
+	if page == nil {
+		page = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			renderError(w, r, http.StatusServiceUnavailable, statusError(http.StatusServiceUnavailable))
+		})
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !enabled.Load() || cfg.allowed(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Cache-Control", "no-store")
+			if cfg.retryAfter > 0 {
+				w.Header().Set("Retry-After", strconv.FormatInt(int64((cfg.retryAfter+time.Second-1)/time.Second), 10))
+			}
+
+			// The page must be sent in full, never as 304 Not Modified or a partial response
+			r = r.Clone(r.Context())
+			for _, h := range []string{"If-None-Match", "If-Modified-Since", "If-Match", "If-Unmodified-Since", "If-Range", "Range"} {
+				r.Header.Del(h)
+			}
+
+			page.ServeHTTP(&maintenanceWriter{ResponseWriter: w}, r)
+		})
+	}
+}
+
+// maintenanceWriter replaces the status code of the maintenance page with 503 Service Unavailable
+// and drops the validators, so that the page is never cached.
+type maintenanceWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+// WriteHeader writes the response header with 503 Service Unavailable.
+func (w *maintenanceWriter) WriteHeader(int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.Header().Del("ETag")
+	w.Header().Del("Last-Modified")
+	w.ResponseWriter.WriteHeader(http.StatusServiceUnavailable)
+}
+
+// Write writes the response body, writing the header first if needed.
+func (w *maintenanceWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusServiceUnavailable)
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the original http.ResponseWriter, so that http.ResponseController can reach it.
+func (w *maintenanceWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,84 @@
+package httpserver_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenanceMiddleware(t *testing.T) {
+	var enabled atomic.Bool
+	page := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("ETag", `"maintenance"`)
+		_, _ = w.Write([]byte("<h1>Back soon</h1>"))
+	})
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	handler := httpserver.MaintenanceMiddleware(&enabled, page,
+		httpserver.WithMaintenanceAllowlist("/health", "/admin/"),
+		httpserver.WithMaintenanceRetryAfter(90*time.Second+time.Millisecond),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			<-release
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	// A request started before the flag is set completes as usual
+	slow := make(chan *httptest.ResponseRecorder)
+	go func() { slow <- get("/slow") }()
+	<-started
+
+	rec := get("/")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "ok", rec.Body.String())
+
+	enabled.Store(true)
+
+	rec = get("/")
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.Equal(t, "<h1>Back soon</h1>", rec.Body.String())
+	require.Equal(t, "91", rec.Header().Get("Retry-After"))
+	require.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	require.Empty(t, rec.Header().Get("ETag"))
+
+	for _, path := range []string{"/health", "/admin/", "/admin/users"} {
+		rec = get(path)
+		require.Equal(t, http.StatusOK, rec.Code, path)
+	}
+	require.Equal(t, http.StatusServiceUnavailable, get("/healthz").Code)
+
+	close(release)
+	rec = <-slow
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	enabled.Store(false)
+	rec = get("/")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Empty(t, rec.Header().Get("Retry-After"))
+}
+
+func TestMaintenanceMiddlewareDefaultPage(t *testing.T) {
+	var enabled atomic.Bool
+	enabled.Store(true)
+
+	handler := httpserver.MaintenanceMiddleware(&enabled, nil)(http.NotFoundHandler())
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.Empty(t, rec.Header().Get("Retry-After"))
+}
@@ -0,0 +1,102 @@
+package httpserver
+
+import (
+	"context"
+	"sync"
+)
+
+// Log levels of the recorded entries.
+const (
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelError = "error"
+)
+
+// LogEntry is a log call recorded by MemoryLogger.
+type LogEntry struct {
+	Level   string
+	Message string
+	Fields  []interface{}
+}
+
+// Field returns the value of the field with the given key.
+func (e LogEntry) Field(key string) (interface{}, bool) {
+	for i := 0; i+1 < len(e.Fields); i += 2 {
+		if e.Fields[i] == key {
+			return e.Fields[i+1], true
+		}
+	}
+	return nil, false
+}
+
+// MemoryLogger is a Logger which records all the log calls in memory, e.g. to assert lifecycle events in tests:
+//
+//	log := &httpserver.MemoryLogger{}
+//	srv, _ := httpserver.New(":8080", handler, httpserver.WithLogger(log))
+//	...
+//	require.True(t, log.Has("server stopped gracefully"))
+//
+// The zero value is ready to use. It is safe for concurrent use.
+type MemoryLogger struct {
+	mu      sync.Mutex
+	entries []LogEntry
+}
+
+// DebugContext records a debug message.
+// Debug messages are only logged by the server if the logger supports them, as MemoryLogger does.
+func (l *MemoryLogger) DebugContext(ctx context.Context, msg string, keyvals ...interface{}) {
+	l.record(LevelDebug, msg, keyvals)
+}
+
+// InfoContext records an info message.
+func (l *MemoryLogger) InfoContext(ctx context.Context, msg string, keyvals ...interface{}) {
+	l.record(LevelInfo, msg, keyvals)
+}
+
+// ErrorContext records an error message.
+func (l *MemoryLogger) ErrorContext(ctx context.Context, msg string, keyvals ...interface{}) {
+	l.record(LevelError, msg, keyvals)
+}
+
+// record appends the entry with a copy of the fields.
+func (l *MemoryLogger) record(level, msg string, keyvals []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, LogEntry{
+		Level:   level,
+		Message: msg,
+		Fields:  append([]interface{}(nil), keyvals...),
+	})
+}
+
+// Entries returns all the recorded entries in order.
+func (l *MemoryLogger) Entries() []LogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]LogEntry(nil), l.entries...)
+}
+
+// Find returns the recorded entries with the given message in order.
+func (l *MemoryLogger) Find(msg string) []LogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var result []LogEntry
+	for _, e := range l.entries {
+		if e.Message == msg {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Has reports whether a message has been recorded.
+func (l *MemoryLogger) Has(msg string) bool {
+	return len(l.Find(msg)) > 0
+}
+
+// Reset drops all the recorded entries.
+func (l *MemoryLogger) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = nil
+}
@@ -0,0 +1,62 @@
+package httpserver_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLogger(t *testing.T) {
+	t.Run("lifecycle", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "localhost:0")
+		require.NoError(t, err)
+
+		log := &httpserver.MemoryLogger{}
+		server, err := httpserver.New(ln.Addr().String(), http.NotFoundHandler(),
+			httpserver.WithListener(ln),
+			httpserver.WithLogger(log),
+		)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		require.NoError(t, server.Start(ctx))
+
+		require.True(t, log.Has("starting HTTP server"))
+		require.True(t, log.Has("server stopped gracefully"))
+		require.False(t, log.Has("server stopped with error"))
+
+		listening := log.Find("listening")
+		require.Len(t, listening, 1)
+		require.Equal(t, httpserver.LevelInfo, listening[0].Level)
+		addr, ok := listening[0].Field("addr")
+		require.True(t, ok)
+		require.Equal(t, ln.Addr().String(), addr)
+
+		log.Reset()
+		require.Empty(t, log.Entries())
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		log := &httpserver.MemoryLogger{}
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				log.InfoContext(context.Background(), "info", "key", "value")
+				log.ErrorContext(context.Background(), "error")
+			}()
+		}
+		wg.Wait()
+
+		require.Len(t, log.Entries(), 100)
+		require.Len(t, log.Find("error"), 50)
+		require.Equal(t, httpserver.LevelError, log.Find("error")[0].Level)
+	})
+}
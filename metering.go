@@ -0,0 +1,45 @@
+package httpserver
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// countingReader wraps a request body and counts the bytes read from it.
+type countingReader struct {
+	io.ReadCloser
+	read int64
+}
+
+// Read reads from the underlying body and counts the bytes.
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.read += int64(n)
+	return n, err
+}
+
+// MeteringMiddleware counts the request and response body bytes of every request
+// and passes them to the record callback after the request is handled, e.g. to meter egress per tenant.
+// The callback receives the request context, so it can extract request-scoped values like the tenant ID.
+//
+// Bodies are counted as they are streamed, nothing is buffered, so chunked and streaming responses are supported.
+// The request size is the number of body bytes actually read by the handler;
+// the response size is the number of body bytes written, excluding headers.
+func MeteringMiddleware(record func(ctx context.Context, reqBytes, respBytes int64)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body := &countingReader{ReadCloser: r.Body}
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = body
+			}
+			rw := newResponseWriter(w)
+
+			defer func() {
+				record(r.Context(), body.read, rw.Written())
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package httpserver_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeteringMiddleware(t *testing.T) {
+	type tenantKey struct{}
+
+	var (
+		tenant              string
+		reqBytes, respBytes int64
+	)
+	mw := httpserver.MeteringMiddleware(func(ctx context.Context, in, out int64) {
+		tenant, _ = ctx.Value(tenantKey{}).(string)
+		reqBytes, respBytes = in, out
+	})
+
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		for i := 0; i < 3; i++ {
+			_, _ = w.Write([]byte("chunk"))
+			w.(http.Flusher).Flush()
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("request body"))
+	req = req.WithContext(context.WithValue(req.Context(), tenantKey{}, "acme"))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, "chunkchunkchunk", rec.Body.String())
+	require.Equal(t, "acme", tenant)
+	require.EqualValues(t, len("request body"), reqBytes)
+	require.EqualValues(t, len("chunkchunkchunk"), respBytes)
+}
@@ -0,0 +1,46 @@
+package httpserver
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// MethodOverrideHeader is the header used to tunnel the HTTP method through POST requests.
+const MethodOverrideHeader = "X-HTTP-Method-Override"
+
+// MethodOverrideField is the form field used to tunnel the HTTP method through POST requests.
+const MethodOverrideField = "_method"
+
+// MethodOverrideMiddleware rewrites the method of POST requests carrying the X-HTTP-Method-Override header
+// or the "_method" form field, for clients which can only send GET and POST.
+// The header takes precedence over the form field. Only PUT, PATCH and DELETE can be tunneled,
+// other values are ignored. The form field is read only from url-encoded and multipart form bodies.
+// The middleware must wrap the router so that the rewritten method is used for dispatching.
+func MethodOverrideMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodPost {
+				method := r.Header.Get(MethodOverrideHeader)
+				if method == "" && isFormRequest(r) {
+					method = r.PostFormValue(MethodOverrideField)
+				}
+
+				switch method = strings.ToUpper(strings.TrimSpace(method)); method {
+				case http.MethodPut, http.MethodPatch, http.MethodDelete:
+					r.Method = method
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isFormRequest reports whether the request body is an HTML form.
+func isFormRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data"
+}
@@ -0,0 +1,90 @@
+package httpserver_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMethodOverrideMiddleware(t *testing.T) {
+	var (
+		method string
+		name   string
+	)
+	handler := httpserver.MethodOverrideMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		name = r.FormValue("name")
+	}))
+
+	form := func(values url.Values) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req
+	}
+
+	tests := []struct {
+		name     string
+		req      func() *http.Request
+		expected string
+	}{
+		{
+			name: "header",
+			req: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/", nil)
+				req.Header.Set(httpserver.MethodOverrideHeader, "delete")
+				return req
+			},
+			expected: http.MethodDelete,
+		},
+		{
+			name:     "form field",
+			req:      func() *http.Request { return form(url.Values{"_method": {"PUT"}, "name": {"john"}}) },
+			expected: http.MethodPut,
+		},
+		{
+			name: "header takes precedence",
+			req: func() *http.Request {
+				req := form(url.Values{"_method": {"PUT"}, "name": {"john"}})
+				req.Header.Set(httpserver.MethodOverrideHeader, "PATCH")
+				return req
+			},
+			expected: http.MethodPatch,
+		},
+		{
+			name: "disallowed method",
+			req: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/", nil)
+				req.Header.Set(httpserver.MethodOverrideHeader, "CONNECT")
+				return req
+			},
+			expected: http.MethodPost,
+		},
+		{
+			name: "non-POST request",
+			req: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set(httpserver.MethodOverrideHeader, "DELETE")
+				return req
+			},
+			expected: http.MethodGet,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method, name = "", ""
+			handler.ServeHTTP(httptest.NewRecorder(), tt.req())
+			require.Equal(t, tt.expected, method)
+		})
+	}
+
+	// Form values remain available to the handler
+	handler.ServeHTTP(httptest.NewRecorder(), form(url.Values{"_method": {"DELETE"}, "name": {"john"}}))
+	require.Equal(t, http.MethodDelete, method)
+	require.Equal(t, "john", name)
+}
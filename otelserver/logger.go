@@ -0,0 +1,52 @@
+package otelserver
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/dmitrymomot/httpserver"
+)
+
+// traceLogger is a Logger which adds every message as an event to the span in the context
+// and appends the trace and span IDs to the logged fields.
+type traceLogger struct {
+	httpserver.Logger
+}
+
+// InfoContext logs an info message with the trace context.
+func (l *traceLogger) InfoContext(ctx context.Context, msg string, keyvals ...interface{}) {
+	l.Logger.InfoContext(ctx, msg, l.with(ctx, msg, keyvals)...)
+}
+
+// ErrorContext logs an error message with the trace context.
+func (l *traceLogger) ErrorContext(ctx context.Context, msg string, keyvals ...interface{}) {
+	l.Logger.ErrorContext(ctx, msg, l.with(ctx, msg, keyvals)...)
+}
+
+// DebugContext logs a debug message with the trace context, if the underlying logger supports debug messages.
+func (l *traceLogger) DebugContext(ctx context.Context, msg string, keyvals ...interface{}) {
+	if dl, ok := l.Logger.(debugLogger); ok {
+		dl.DebugContext(ctx, msg, l.with(ctx, msg, keyvals)...)
+	}
+}
+
+// debugLogger is implemented by loggers which support debug messages, e.g. *slog.Logger.
+type debugLogger interface {
+	DebugContext(ctx context.Context, msg string, keyvals ...interface{})
+}
+
+// with records the span event and returns the key-value pairs followed by the trace and span IDs,
+// if the context carries a valid span.
+func (l *traceLogger) with(ctx context.Context, msg string, keyvals []interface{}) []interface{} {
+	span := trace.SpanFromContext(ctx)
+	sc := span.SpanContext()
+	if !sc.IsValid() {
+		return keyvals
+	}
+	span.AddEvent(msg, trace.WithAttributes(eventAttributes(keyvals)...))
+
+	result := make([]interface{}, 0, len(keyvals)+4)
+	result = append(result, keyvals...)
+	return append(result, "trace_id", sc.TraceID().String(), "span_id", sc.SpanID().String())
+}
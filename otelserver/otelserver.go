@@ -0,0 +1,176 @@
+// Package otelserver provides OpenTelemetry tracing for httpserver.
+// It lives in a separate package to keep the OpenTelemetry dependencies out of the core package.
+//
+// Usage:
+//
+//	srv, err := httpserver.New(":8080", handler, otelserver.WithTracing(tracerProvider))
+//
+// Every request gets a server span, which continues the trace from the incoming traceparent header.
+// Lifecycle events, e.g. server start and stop, are added as events to the span found in the context
+// passed to Start, and are logged with its trace and span IDs.
+package otelserver
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/dmitrymomot/httpserver"
+)
+
+// instrumentationName is the name of the tracer, which identifies the instrumentation library.
+const instrumentationName = "github.com/dmitrymomot/httpserver/otelserver"
+
+type option func(*tracing)
+
+// WithPropagator sets the propagator used to extract the trace context from the request headers.
+// By default the W3C Trace Context and Baggage propagators are used.
+func WithPropagator(p propagation.TextMapPropagator) option {
+	return func(t *tracing) {
+		t.propagator = p
+	}
+}
+
+// WithSpanNameFormatter sets the function which names the request spans.
+// By default the span is named after the request method, e.g. "GET",
+// to keep the span names low-cardinality.
+func WithSpanNameFormatter(fn func(r *http.Request) string) option {
+	return func(t *tracing) {
+		t.spanName = fn
+	}
+}
+
+// tracing implements httpserver.Instrumentation with OpenTelemetry.
+type tracing struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	spanName   func(r *http.Request) string
+}
+
+// New creates the OpenTelemetry instrumentation with the given tracer provider.
+// If the provider is nil, the global one is used.
+// Use it with httpserver.WithInstrumentation, or use WithTracing directly.
+func New(tp trace.TracerProvider, opts ...option) httpserver.Instrumentation {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	t := &tracing{
+		tracer:     tp.Tracer(instrumentationName),
+		propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+		spanName: func(r *http.Request) string {
+			return r.Method
+		},
+	}
+	for _, o := range opts {
+		o(t)
+	}
+	return t
+}
+
+// WithTracing is a server option which enables the OpenTelemetry tracing with the given tracer provider.
+// If the provider is nil, the global one is used.
+func WithTracing(tp trace.TracerProvider, opts ...option) func(*httpserver.Server) {
+	return httpserver.WithInstrumentation(New(tp, opts...))
+}
+
+// Handler wraps the handler with a middleware creating a server span per request.
+// The span status is set to error for 5xx responses, as 4xx are client errors from the server's point of view.
+func (t *tracing) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := t.propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		ctx, span := t.tracer.Start(ctx, t.spanName(r),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+				semconv.URLScheme(scheme),
+				semconv.ServerAddress(r.Host),
+				semconv.UserAgentOriginal(r.UserAgent()),
+				semconv.NetworkProtocolVersion(fmt.Sprintf("%d.%d", r.ProtoMajor, r.ProtoMinor)),
+			),
+		)
+		defer span.End()
+
+		rw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(rw, r.WithContext(ctx))
+
+		status := rw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// Logger wraps the server logger, so that the lifecycle events carry the trace context.
+func (t *tracing) Logger(log httpserver.Logger) httpserver.Logger {
+	return &traceLogger{Logger: log}
+}
+
+// statusWriter records the response status code.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code and writes it to the underlying writer.
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write writes the data, recording the implicit 200 status code if no header is written yet.
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher for the handlers which do not use http.ResponseController.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, e.g. for WebSocket upgrades.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// eventAttributes converts the log key-value pairs to span event attributes.
+func eventAttributes(keyvals []interface{}) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(keyvals)/2)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		key, ok := keyvals[i].(string)
+		if !ok {
+			continue
+		}
+		attrs = append(attrs, attribute.String(key, fmt.Sprint(keyvals[i+1])))
+	}
+	return attrs
+}
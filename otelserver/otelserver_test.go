@@ -0,0 +1,162 @@
+package otelserver_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/dmitrymomot/httpserver/otelserver"
+)
+
+// newTracedHandler returns the test handler wrapped with the tracing middleware and the recorder of its spans.
+func newTracedHandler() (http.Handler, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	return otelserver.New(tp).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fail":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/missing":
+			http.NotFound(w, r)
+		default:
+			_, _ = w.Write([]byte("ok"))
+		}
+	})), recorder
+}
+
+func TestHandler(t *testing.T) {
+	t.Run("propagates traceparent", func(t *testing.T) {
+		handler, recorder := newTracedHandler()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		require.Equal(t, "GET", spans[0].Name())
+		require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+		require.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+		require.Equal(t, codes.Unset, spans[0].Status().Code)
+		require.Contains(t, spans[0].Attributes(), semconv.HTTPResponseStatusCode(http.StatusOK))
+	})
+
+	t.Run("client error", func(t *testing.T) {
+		handler, recorder := newTracedHandler()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		require.Equal(t, codes.Unset, spans[0].Status().Code)
+		require.Contains(t, spans[0].Attributes(), semconv.HTTPResponseStatusCode(http.StatusNotFound))
+	})
+
+	t.Run("server error", func(t *testing.T) {
+		handler, recorder := newTracedHandler()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		require.Equal(t, codes.Error, spans[0].Status().Code)
+		require.Contains(t, spans[0].Attributes(), semconv.HTTPResponseStatusCode(http.StatusInternalServerError))
+	})
+}
+
+func TestWithTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv, err := httpserver.New(ln.Addr().String(), http.NotFoundHandler(),
+		httpserver.WithListener(ln),
+		otelserver.WithTracing(tp),
+	)
+	require.NoError(t, err)
+
+	ctx, lifecycle := tp.Tracer("test").Start(context.Background(), "lifecycle")
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Start(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusNotFound
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+	lifecycle.End()
+
+	var events []string
+	var requests int
+	for _, span := range recorder.Ended() {
+		switch span.Name() {
+		case "lifecycle":
+			for _, e := range span.Events() {
+				events = append(events, e.Name)
+			}
+		case "GET":
+			requests++
+		}
+	}
+	require.Equal(t, 1, requests)
+	require.Contains(t, events, "starting HTTP server")
+	require.Contains(t, events, "stopping HTTP server")
+	require.Contains(t, events, "server stopped gracefully")
+}
+
+func TestWithTracingDebugLogs(t *testing.T) {
+	tp := sdktrace.NewTracerProvider()
+	logger := &httpserver.MemoryLogger{}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv, err := httpserver.New(ln.Addr().String(), http.NotFoundHandler(),
+		httpserver.WithListener(ln),
+		httpserver.WithLogger(logger),
+		httpserver.WithConnStateLogging(),
+		otelserver.WithTracing(tp),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Start(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusNotFound
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	entries := logger.Find("connection state changed")
+	require.NotEmpty(t, entries)
+	require.Equal(t, httpserver.LevelDebug, entries[0].Level)
+}
@@ -0,0 +1,108 @@
+package httpserver
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// acceptGate blocks accepting new connections while the server is paused.
+type acceptGate struct {
+	mu      sync.Mutex
+	resumed chan struct{} // nil while not paused, closed on resume
+}
+
+// pause closes the gate. It reports false if the gate is already closed.
+func (g *acceptGate) pause() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resumed != nil {
+		return false
+	}
+	g.resumed = make(chan struct{})
+	return true
+}
+
+// resume opens the gate. It reports false if the gate is not closed.
+func (g *acceptGate) resume() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.resumed == nil {
+		return false
+	}
+	close(g.resumed)
+	g.resumed = nil
+	return true
+}
+
+// wait returns a channel which is closed once the gate opens, or nil if the gate is open.
+func (g *acceptGate) wait() <-chan struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.resumed
+}
+
+// gatedListener wraps a net.Listener and holds the accepted connections while the gate is closed.
+type gatedListener struct {
+	net.Listener
+	gate      *acceptGate
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// Accept waits for and returns the next connection to the listener, blocking while the server is paused.
+// A connection accepted right before the pause is held until the server resumes.
+func (l *gatedListener) Accept() (net.Conn, error) {
+	if err := l.waitOpen(); err != nil {
+		return nil, err
+	}
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if err := l.waitOpen(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// waitOpen blocks while the gate is closed. It returns net.ErrClosed if the listener is closed meanwhile.
+func (l *gatedListener) waitOpen() error {
+	resumed := l.gate.wait()
+	if resumed == nil {
+		return nil
+	}
+	select {
+	case <-resumed:
+		return nil
+	case <-l.closed:
+		return net.ErrClosed
+	}
+}
+
+// Close closes the listener and unblocks the pending Accept call.
+func (l *gatedListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return l.Listener.Close()
+}
+
+// Pause stops accepting new connections on all the listeners of the server, e.g. for a maintenance window,
+// without closing the listeners: new connections wait in the backlog of the operating system
+// until Resume is called, or are dropped by it if the backlog is full or the client gives up.
+// Existing connections are kept alive and their requests are served as usual.
+// Unlike maintenance mode, which responds with 503 Service Unavailable, paused servers do not respond at all.
+// The shutdown is not affected by the pause. Calling Pause on a paused server has no effect.
+func (s *Server) Pause() {
+	if s.acceptGate.pause() {
+		s.log.InfoContext(context.Background(), "paused accepting new connections")
+	}
+}
+
+// Resume resumes accepting new connections after Pause.
+// Calling Resume on a server which is not paused has no effect.
+func (s *Server) Resume() {
+	if s.acceptGate.resume() {
+		s.log.InfoContext(context.Background(), "resumed accepting new connections")
+	}
+}
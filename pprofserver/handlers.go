@@ -0,0 +1,163 @@
+package pprofserver
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pprofPrefix is the path prefix of the endpoints.
+const pprofPrefix = "/debug/pprof/"
+
+// newMux returns the mux serving the profiling endpoints.
+// They follow the protocol of net/http/pprof, so that "go tool pprof" and "go tool trace" work as usual.
+func newMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc(pprofPrefix, serveIndex)
+	mux.HandleFunc(pprofPrefix+"cmdline", serveCmdline)
+	mux.HandleFunc(pprofPrefix+"profile", serveCPUProfile)
+	mux.HandleFunc(pprofPrefix+"symbol", serveSymbol)
+	mux.HandleFunc(pprofPrefix+"trace", serveTrace)
+	return mux
+}
+
+// serveIndex lists the available profiles, or serves the named profile, e.g. /debug/pprof/heap.
+func serveIndex(w http.ResponseWriter, r *http.Request) {
+	if name := strings.TrimPrefix(r.URL.Path, pprofPrefix); name != "" {
+		serveProfile(w, r, name)
+		return
+	}
+
+	profiles := pprof.Profiles()
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name() < profiles[j].Name() })
+
+	var b bytes.Buffer
+	b.WriteString("<html><head><title>/debug/pprof/</title></head><body>\n<p>Profiles:</p>\n<table>\n")
+	for _, p := range profiles {
+		name := html.EscapeString(p.Name())
+		fmt.Fprintf(&b, "<tr><td>%d</td><td><a href=\"%s?debug=1\">%s</a></td></tr>\n", p.Count(), name, name)
+	}
+	b.WriteString("<tr><td></td><td><a href=\"profile\">profile</a></td></tr>\n")
+	b.WriteString("<tr><td></td><td><a href=\"trace?seconds=1\">trace</a></td></tr>\n")
+	b.WriteString("</table>\n</body></html>\n")
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(b.Bytes())
+}
+
+// serveProfile writes the named runtime profile, in the text format if the "debug" parameter is not 0.
+// The "gc" parameter of the heap profile runs a garbage collection first.
+func serveProfile(w http.ResponseWriter, r *http.Request, name string) {
+	p := pprof.Lookup(name)
+	if p == nil {
+		http.Error(w, "Unknown profile", http.StatusNotFound)
+		return
+	}
+	debug, _ := strconv.Atoi(r.FormValue("debug"))
+	if name == "heap" && r.FormValue("gc") != "" && r.FormValue("gc") != "0" {
+		runtime.GC()
+	}
+
+	if debug != 0 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	}
+	_ = p.WriteTo(w, debug)
+}
+
+// serveCmdline writes the command line of the program, with the arguments separated by NUL bytes.
+func serveCmdline(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = io.WriteString(w, strings.Join(os.Args, "\x00"))
+}
+
+// serveCPUProfile writes the CPU profile for the duration set by the "seconds" parameter, 30 seconds by default.
+func serveCPUProfile(w http.ResponseWriter, r *http.Request) {
+	seconds, err := strconv.ParseInt(r.FormValue("seconds"), 10, 64)
+	if err != nil || seconds <= 0 {
+		seconds = 30
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="profile"`)
+	if err := pprof.StartCPUProfile(w); err != nil {
+		w.Header().Del("Content-Disposition")
+		http.Error(w, "Could not enable CPU profiling: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sleep(r, time.Duration(seconds)*time.Second)
+	pprof.StopCPUProfile()
+}
+
+// serveTrace writes the execution trace for the duration set by the "seconds" parameter, 1 second by default.
+func serveTrace(w http.ResponseWriter, r *http.Request) {
+	seconds, err := strconv.ParseFloat(r.FormValue("seconds"), 64)
+	if err != nil || seconds <= 0 {
+		seconds = 1
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="trace"`)
+	if err := trace.Start(w); err != nil {
+		w.Header().Del("Content-Disposition")
+		http.Error(w, "Could not enable tracing: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sleep(r, time.Duration(seconds*float64(time.Second)))
+	trace.Stop()
+}
+
+// sleep waits for the duration or until the client goes away.
+func sleep(r *http.Request, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-r.Context().Done():
+	}
+}
+
+// serveSymbol resolves the program counters, sent as "+"-separated hex numbers in the POST body
+// or the query string, to function names, one "<pc> <name>" line per resolved counter.
+func serveSymbol(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	var b bytes.Buffer
+	// The number of symbols is not known; a non-zero value tells the client that symbols are available
+	b.WriteString("num_symbols: 1\n")
+
+	var input *bufio.Reader
+	if r.Method == http.MethodPost {
+		input = bufio.NewReader(r.Body)
+	} else {
+		input = bufio.NewReader(strings.NewReader(r.URL.RawQuery))
+	}
+	for {
+		word, err := input.ReadSlice('+')
+		if err == nil {
+			word = word[:len(word)-1]
+		}
+		if pc, perr := strconv.ParseUint(string(word), 0, 64); perr == nil && pc != 0 {
+			if fn := runtime.FuncForPC(uintptr(pc)); fn != nil {
+				fmt.Fprintf(&b, "%#x %s\n", pc, fn.Name())
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	_, _ = w.Write(b.Bytes())
+}
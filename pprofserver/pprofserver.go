@@ -0,0 +1,61 @@
+// Package pprofserver provides the profiling endpoints of net/http/pprof guarded by a bearer token.
+// The endpoints are implemented on top of runtime/pprof, without importing net/http/pprof,
+// whose import registers unprotected endpoints on http.DefaultServeMux: with this package they are never
+// exposed by http.ListenAndServe(addr, nil) or httpserver.RunDefault.
+//
+// Usage, on an admin port which is not exposed to the public:
+//
+//	srv, err := httpserver.New(":8080", handler,
+//		httpserver.WithAdditionalListener("127.0.0.1:6060", pprofserver.ProtectedPprofHandler(token), nil),
+//	)
+//
+// Then profile with the token, e.g.:
+//
+//	curl -H "Authorization: Bearer $TOKEN" http://127.0.0.1:6060/debug/pprof/heap > heap.out
+package pprofserver
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// ProtectedPprofHandler returns an http.Handler serving the pprof endpoints under "/debug/pprof/"
+// to requests with the "Authorization: Bearer <token>" header only; other requests get 401 Unauthorized.
+// The token is compared in constant time. It panics if the token is empty.
+// The endpoints are the ones of net/http/pprof: the index, the named profiles, e.g. "heap" or "goroutine",
+// "profile", "trace", "cmdline" and "symbol". Unlike net/http/pprof, nothing is registered
+// on http.DefaultServeMux.
+//
+// The token is sent in clear text over plain HTTP, so serve the handler over TLS or on a private network,
+// ideally restricted to the allowed client addresses as well, for defense in depth.
+func ProtectedPprofHandler(token string) http.Handler {
+	if token == "" {
+		panic("pprofserver: empty token")
+	}
+	expected := sha256.Sum256([]byte(token))
+
+	mux := newMux()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !validToken(r, expected) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="pprof"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// validToken reports whether the request carries the bearer token with the expected hash.
+// Hashes are compared, so that the comparison takes the same time regardless of the token length.
+func validToken(r *http.Request, expected [sha256.Size]byte) bool {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return false
+	}
+	actual := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return subtle.ConstantTimeCompare(actual[:], expected[:]) == 1
+}
@@ -0,0 +1,113 @@
+package pprofserver_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/dmitrymomot/httpserver/pprofserver"
+)
+
+func TestProtectedPprofHandler(t *testing.T) {
+	handler := pprofserver.ProtectedPprofHandler("secret")
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+	}{
+		{name: "valid token", authorization: "Bearer secret", status: http.StatusOK},
+		{name: "case-insensitive scheme", authorization: "bearer secret", status: http.StatusOK},
+		{name: "invalid token", authorization: "Bearer wrong", status: http.StatusUnauthorized},
+		{name: "token prefix", authorization: "Bearer secre", status: http.StatusUnauthorized},
+		{name: "basic auth", authorization: "Basic c2VjcmV0", status: http.StatusUnauthorized},
+		{name: "no token", status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/debug/pprof/goroutine?debug=1", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.status, rec.Code)
+			if tt.status == http.StatusUnauthorized {
+				require.Equal(t, `Bearer realm="pprof"`, rec.Header().Get("WWW-Authenticate"))
+				require.NotContains(t, rec.Body.String(), "goroutine")
+			} else {
+				require.Contains(t, rec.Body.String(), "goroutine")
+			}
+		})
+	}
+
+	t.Run("empty token", func(t *testing.T) {
+		require.Panics(t, func() { pprofserver.ProtectedPprofHandler("") })
+	})
+}
+
+func TestProtectedPprofHandlerEndpoints(t *testing.T) {
+	handler := pprofserver.ProtectedPprofHandler("secret")
+	do := func(method, target string, body io.Reader) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, body)
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("index", func(t *testing.T) {
+		rec := do(http.MethodGet, "/debug/pprof/", nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+		require.Contains(t, rec.Body.String(), `href="heap?debug=1"`)
+		require.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	})
+
+	t.Run("binary profile", func(t *testing.T) {
+		rec := do(http.MethodGet, "/debug/pprof/heap?gc=1", nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "application/octet-stream", rec.Header().Get("Content-Type"))
+		require.Equal(t, `attachment; filename="heap"`, rec.Header().Get("Content-Disposition"))
+		// Profiles are gzip-compressed protocol buffers
+		require.True(t, bytes.HasPrefix(rec.Body.Bytes(), []byte{0x1f, 0x8b}))
+	})
+
+	t.Run("unknown profile", func(t *testing.T) {
+		require.Equal(t, http.StatusNotFound, do(http.MethodGet, "/debug/pprof/missing", nil).Code)
+	})
+
+	t.Run("cmdline", func(t *testing.T) {
+		rec := do(http.MethodGet, "/debug/pprof/cmdline", nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, strings.Join(os.Args, "\x00"), rec.Body.String())
+	})
+
+	t.Run("symbol", func(t *testing.T) {
+		pc := reflect.ValueOf(pprofserver.ProtectedPprofHandler).Pointer()
+		rec := do(http.MethodPost, "/debug/pprof/symbol", strings.NewReader(fmt.Sprintf("%#x+0x0", pc)))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, fmt.Sprintf("num_symbols: 1\n%#x github.com/dmitrymomot/httpserver/pprofserver.ProtectedPprofHandler\n", pc), rec.Body.String())
+	})
+
+	t.Run("trace", func(t *testing.T) {
+		rec := do(http.MethodGet, "/debug/pprof/trace?seconds=0.05", nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.True(t, bytes.HasPrefix(rec.Body.Bytes(), []byte("go 1.")))
+	})
+}
+
+func TestDefaultServeMuxUntouched(t *testing.T) {
+	_, pattern := http.DefaultServeMux.Handler(httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+	require.Empty(t, pattern)
+}
@@ -0,0 +1,109 @@
+package httpserver
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// remoteAddr returns the IP address of the immediate peer of the request.
+func remoteAddr(r *http.Request) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// peerInPrefixes reports whether the immediate peer of the request belongs to any of the prefixes.
+func peerInPrefixes(r *http.Request, prefixes []netip.Prefix) bool {
+	if len(prefixes) == 0 {
+		return false
+	}
+	addr, ok := remoteAddr(r)
+	return ok && addrInPrefixes(addr, prefixes)
+}
+
+// addrInPrefixes reports whether the address belongs to any of the prefixes.
+func addrInPrefixes(addr netip.Addr, prefixes []netip.Prefix) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the IP address of the client which sent the request.
+// If the immediate peer belongs to one of the trusted proxy prefixes, the X-Forwarded-For chain is walked
+// from the right, skipping the trusted proxies, and the first untrusted address is the client;
+// without X-Forwarded-For, the X-Real-IP header is used. Otherwise the headers are ignored,
+// since anyone can send them, and the immediate peer is the client.
+// A malformed entry stops the walk at the last valid address, so it cannot be used to spoof the client.
+//
+// It returns the zero netip.Addr if r.RemoteAddr is not an IP address, e.g. for a Unix socket listener.
+func ClientIP(r *http.Request, trustedProxies []netip.Prefix) netip.Addr {
+	client, ok := remoteAddr(r)
+	if !ok || !addrInPrefixes(client, trustedProxies) {
+		return client
+	}
+
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				break
+			}
+			client = addr.Unmap()
+			if !addrInPrefixes(client, trustedProxies) {
+				break
+			}
+		}
+		return client
+	}
+
+	if addr, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return addr.Unmap()
+	}
+	return client
+}
+
+// requestHost returns the host the request was sent to, without the port and lower-cased.
+// The X-Forwarded-Host header is used only if the immediate peer is a trusted proxy;
+// if the proxy appended to the list, the first (client-facing) value is used.
+func requestHost(r *http.Request, trustedProxies []netip.Prefix) string {
+	host := r.Host
+	if fh := r.Header.Get("X-Forwarded-Host"); fh != "" && peerInPrefixes(r, trustedProxies) {
+		host, _, _ = strings.Cut(fh, ",")
+		host = strings.TrimSpace(host)
+	}
+	return strings.ToLower(stripPort(host))
+}
+
+// stripPort removes the port from the host, keeping IPv6 literals intact.
+func stripPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return strings.Trim(h, "[]")
+	}
+	return strings.Trim(host, "[]")
+}
+
+// requestScheme returns the scheme the request was sent with, "http" or "https".
+// The X-Forwarded-Proto header is used only if the immediate peer is a trusted proxy;
+// if the proxy appended to the list, the first (client-facing) value is used.
+func requestScheme(r *http.Request, trustedProxies []netip.Prefix) string {
+	if fp := r.Header.Get("X-Forwarded-Proto"); fp != "" && peerInPrefixes(r, trustedProxies) {
+		proto, _, _ := strings.Cut(fp, ",")
+		return strings.ToLower(strings.TrimSpace(proto))
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
@@ -0,0 +1,60 @@
+package httpserver_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("fd00::/8")}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        []string
+		realIP     string
+		expected   string
+	}{
+		{name: "direct client", remoteAddr: "203.0.113.7:1234", expected: "203.0.113.7"},
+		{name: "spoofed XFF from untrusted peer", remoteAddr: "203.0.113.7:1234", xff: []string{"1.2.3.4"}, expected: "203.0.113.7"},
+		{name: "spoofed X-Real-IP from untrusted peer", remoteAddr: "203.0.113.7:1234", realIP: "1.2.3.4", expected: "203.0.113.7"},
+		{name: "trusted proxy", remoteAddr: "10.0.0.1:1234", xff: []string{"198.51.100.1"}, expected: "198.51.100.1"},
+		{name: "chain of trusted proxies", remoteAddr: "10.0.0.1:1234", xff: []string{"198.51.100.1, 10.0.0.2, 10.0.0.3"}, expected: "198.51.100.1"},
+		{name: "spoofed entry before the client", remoteAddr: "10.0.0.1:1234", xff: []string{"1.2.3.4, 198.51.100.1"}, expected: "198.51.100.1"},
+		{name: "spoofed trusted entry before the client", remoteAddr: "10.0.0.1:1234", xff: []string{"10.9.9.9, 198.51.100.1"}, expected: "198.51.100.1"},
+		{name: "multiple headers", remoteAddr: "10.0.0.1:1234", xff: []string{"1.2.3.4", "198.51.100.1, 10.0.0.2"}, expected: "198.51.100.1"},
+		{name: "malformed entry", remoteAddr: "10.0.0.1:1234", xff: []string{"198.51.100.1, garbage, 10.0.0.2"}, expected: "10.0.0.2"},
+		{name: "only trusted entries", remoteAddr: "10.0.0.1:1234", xff: []string{"10.0.0.2"}, expected: "10.0.0.2"},
+		{name: "X-Real-IP from trusted peer", remoteAddr: "10.0.0.1:1234", realIP: "198.51.100.1", expected: "198.51.100.1"},
+		{name: "malformed X-Real-IP", remoteAddr: "10.0.0.1:1234", realIP: "garbage", expected: "10.0.0.1"},
+		{name: "IPv6", remoteAddr: "[fd00::1]:1234", xff: []string{"2001:db8::1"}, expected: "2001:db8::1"},
+		{name: "IPv4-mapped IPv6", remoteAddr: "[::ffff:203.0.113.7]:1234", expected: "203.0.113.7"},
+		{name: "unix socket", remoteAddr: "@", expected: "invalid IP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			require.Equal(t, tt.expected, httpserver.ClientIP(r, trusted).String())
+		})
+	}
+
+	t.Run("no trusted proxies", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		r.Header.Set("X-Forwarded-For", "198.51.100.1")
+		require.Equal(t, "10.0.0.1", httpserver.ClientIP(r, nil).String())
+	})
+}
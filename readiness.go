@@ -0,0 +1,56 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+)
+
+// createReadinessFile creates the readiness file, if configured, once the server is listening.
+// Errors are logged, since the file is a signal for external tools and must not break the server lifecycle.
+func (s *Server) createReadinessFile(ctx context.Context) {
+	if s.readinessFile == "" {
+		return
+	}
+	f, err := os.Create(s.readinessFile)
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to create readiness file", "path", s.readinessFile, "error", err)
+		return
+	}
+	s.log.InfoContext(ctx, "created readiness file", "path", s.readinessFile)
+}
+
+// removeReadinessFile removes the readiness file, if configured.
+// It is called at the start of the shutdown and once more when the server stops, so a missing file is not an error.
+func (s *Server) removeReadinessFile(ctx context.Context) {
+	if s.readinessFile == "" {
+		return
+	}
+	if err := os.Remove(s.readinessFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		s.log.ErrorContext(ctx, "failed to remove readiness file", "path", s.readinessFile, "error", err)
+	}
+}
+
+// ReadinessHandler returns an http.HandlerFunc which reports whether the server accepts new traffic:
+// 200 OK while it is serving, and 503 Service Unavailable as soon as the shutdown begins,
+// so that load balancers and the Kubernetes readiness probe take the instance out of rotation.
+// Unlike HealthHandler, it does not run the health checks, since a failing dependency
+// does not make other instances any better at serving the request.
+func (s *Server) ReadinessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp, status := healthResponse{Status: "ok"}, http.StatusOK
+		if s.ShuttingDown() {
+			resp, status = healthResponse{Status: "shutting down"}, http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
@@ -0,0 +1,76 @@
+package httpserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxDecompressedSize(t *testing.T) {
+	var readErr error
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []byte
+		body, readErr = io.ReadAll(r.Body)
+		if readErr != nil {
+			return
+		}
+		_, _ = w.Write(body)
+	})
+	s, err := New("localhost:9999", handler, WithMaxDecompressedSize(1<<20))
+	require.NoError(t, err)
+
+	gzipped := func(data []byte) *bytes.Buffer {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write(data)
+		require.NoError(t, zw.Close())
+		return &buf
+	}
+	send := func(body io.Reader) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", body)
+		req.Header.Set("Content-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		s.httpServer.Handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("decoded", func(t *testing.T) {
+		rec := send(gzipped([]byte("hello")))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "hello", rec.Body.String())
+	})
+
+	t.Run("exactly the limit", func(t *testing.T) {
+		rec := send(gzipped(make([]byte, 1<<20)))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, 1<<20, rec.Body.Len())
+	})
+
+	t.Run("bomb", func(t *testing.T) {
+		bomb := gzipped(make([]byte, 100<<20))
+		require.Less(t, bomb.Len(), 256<<10)
+
+		rec := send(bomb)
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		require.True(t, errors.Is(readErr, ErrDecompressedBodyTooLarge))
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		rec := send(strings.NewReader("not gzip"))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("identity", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		s.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("plain")))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "plain", rec.Body.String())
+	})
+}
@@ -0,0 +1,107 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// defaultTimeoutResponse is a minimal JSON 504 response.
+var defaultTimeoutResponse = &fixedResponse{
+	status:      http.StatusGatewayTimeout,
+	contentType: "application/json",
+	body:        []byte(`{"error":"request timeout"}` + "\n"),
+}
+
+// requestTimeoutMiddleware sets a deadline on the request context, so that context-aware handlers abort in time.
+// If the deadline is exceeded and the handler returns without writing a response, the timeout response is sent.
+func (s *Server) requestTimeoutMiddleware(next http.Handler) http.Handler {
+	timeout := s.requestTimeout
+	resp := s.timeoutResponse
+	if resp == nil {
+		resp = defaultTimeoutResponse
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		rw := newResponseWriter(w)
+		next.ServeHTTP(rw, r.WithContext(ctx))
+
+		if !rw.wroteHeader && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			resp.write(rw, r)
+		}
+	})
+}
+
+// streamTimeoutMiddleware sets the read and write deadlines of every HTTP/2 stream, along with the deadline
+// of the request context, so that a slow stream is reset on its own without affecting the other streams
+// multiplexed over the connection. HTTP/1.x requests are left to the connection-level timeouts.
+func (s *Server) streamTimeoutMiddleware(next http.Handler) http.Handler {
+	timeout := s.streamTimeout
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		deadline := time.Now().Add(timeout)
+		rc := http.NewResponseController(w)
+		_ = rc.SetReadDeadline(deadline)
+		_ = rc.SetWriteDeadline(deadline)
+
+		ctx, cancel := context.WithDeadline(r.Context(), deadline)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// connContextKey is the context key for the connection a request arrived on.
+type connContextKey struct{}
+
+// setConnContext makes the connection available in every request context,
+// so that the server can close it, e.g. when the absolute request timeout expires.
+// The ConnContext of a preconfigured http.Server, if any, is preserved.
+func (s *Server) setConnContext() {
+	next := s.httpServer.ConnContext
+	s.httpServer.ConnContext = func(ctx context.Context, c net.Conn) context.Context {
+		if next != nil {
+			ctx = next(ctx, c)
+		}
+		return context.WithValue(ctx, connContextKey{}, c)
+	}
+}
+
+// absoluteTimeoutMiddleware starts a watchdog for every request which closes the connection
+// if the request is still being handled after the absolute timeout, and logs the killed request.
+func (s *Server) absoluteTimeoutMiddleware(next http.Handler) http.Handler {
+	timeout := s.absoluteRequestTimeout
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, ok := r.Context().Value(connContextKey{}).(net.Conn)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		watchdog := time.AfterFunc(timeout, func() {
+			fields := []interface{}{
+				"method", r.Method,
+				"path", r.URL.Path,
+				"remote_addr", r.RemoteAddr,
+				"duration", time.Since(start),
+				"timeout", timeout,
+			}
+			if s.clientIPLogging {
+				fields = append(fields, "client_ip", ClientIP(r, s.trustedProxies).String())
+			}
+			s.log.ErrorContext(r.Context(), "request exceeded the absolute timeout, closing the connection", fields...)
+			_ = conn.Close()
+		})
+		defer watchdog.Stop()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,16 @@
+package httpserver
+
+import "net/http"
+
+// responseHeadersMiddleware sets the headers configured with WithResponseHeaders on every response
+// before the handler runs, so that the handler can still override them.
+func (s *Server) responseHeadersMiddleware(next http.Handler) http.Handler {
+	headers := s.responseHeaders
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		for key, values := range headers {
+			h[key] = append([]string(nil), values...)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
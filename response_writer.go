@@ -0,0 +1,218 @@
+package httpserver
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// fixedResponse is a preconfigured response sent by the middlewares of the package,
+// e.g. to requests arriving during the shutdown or exceeding the request timeout.
+type fixedResponse struct {
+	status      int
+	contentType string
+	body        []byte
+}
+
+// write sends the response. The body is omitted for HEAD requests.
+func (resp *fixedResponse) write(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", resp.contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(resp.body)))
+	w.WriteHeader(resp.status)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(resp.body)
+	}
+}
+
+// responseWriter wraps http.ResponseWriter to record the response status and size
+// and to run a hook right before the response header is written.
+// It is shared by the middlewares of the package.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	written     int64
+	wroteHeader bool
+
+	// beforeWriteHeader, if set, is called once right before the header is written.
+	beforeWriteHeader func(status int)
+}
+
+// newResponseWriter wraps the given http.ResponseWriter.
+func newResponseWriter(w http.ResponseWriter) *responseWriter {
+	return &responseWriter{ResponseWriter: w, status: http.StatusOK}
+}
+
+// WriteHeader records the status code and writes the response header.
+func (w *responseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	// Informational responses do not complete the header
+	if status >= 100 && status < 200 && status != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	w.wroteHeader = true
+	w.status = status
+	if w.beforeWriteHeader != nil {
+		w.beforeWriteHeader(status)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes the response body, writing the header first if needed.
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+	return n, err
+}
+
+// ReadFrom copies the response body from the reader,
+// preserving the optimized path (e.g. sendfile) of the underlying http.ResponseWriter.
+func (w *responseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := io.Copy(w.ResponseWriter, r)
+	w.written += n
+	return n, err
+}
+
+// Status returns the response status code.
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+// Written returns the number of bytes of the response body written so far.
+func (w *responseWriter) Written() int64 {
+	return w.written
+}
+
+// Flush sends any buffered data to the client.
+func (w *responseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack lets the caller take over the connection.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the original http.ResponseWriter, so that http.ResponseController can reach it.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// bufferedWriter buffers the response until it is committed to the underlying http.ResponseWriter,
+// so that a middleware can inspect or replace it. The header map is separate from the underlying one until then.
+// The response is committed as is once the body exceeds the limit or the handler flushes it.
+// A hijacked connection is handed over without sending the buffered response, unless the handler has written one.
+type bufferedWriter struct {
+	http.ResponseWriter
+	header      http.Header
+	status      int
+	wroteHeader bool
+	limit       int
+	buf         bytes.Buffer
+	committed   bool
+}
+
+// newBufferedWriter wraps the given http.ResponseWriter, buffering up to limit bytes of the body.
+// The header map buffers the response header, usually a clone of the header set so far by outer middlewares.
+func newBufferedWriter(w http.ResponseWriter, header http.Header, limit int) *bufferedWriter {
+	return &bufferedWriter{ResponseWriter: w, header: header, status: http.StatusOK, limit: limit}
+}
+
+// Header returns the buffered header map, or the header map of the underlying writer once committed.
+func (w *bufferedWriter) Header() http.Header {
+	if w.committed {
+		return w.ResponseWriter.Header()
+	}
+	return w.header
+}
+
+// WriteHeader records the status code. Informational responses, e.g. 103 Early Hints,
+// are sent right away with the buffered header, since they precede the final response.
+func (w *bufferedWriter) WriteHeader(status int) {
+	if w.committed {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if w.wroteHeader {
+		return
+	}
+	if status >= 100 && status < 200 && status != http.StatusSwitchingProtocols {
+		h := w.ResponseWriter.Header()
+		for key, values := range w.header {
+			h[key] = values
+		}
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+}
+
+// Write buffers the body, committing the response once the buffer limit is exceeded.
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	if w.committed {
+		return w.ResponseWriter.Write(b)
+	}
+	w.wroteHeader = true
+	if w.buf.Len()+len(b) > w.limit {
+		w.commit()
+		return w.ResponseWriter.Write(b)
+	}
+	return w.buf.Write(b)
+}
+
+// Flush commits the response and flushes it to the client.
+func (w *bufferedWriter) Flush() {
+	w.commit()
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack lets the caller take over the connection, e.g. to switch to WebSocket.
+// Nothing is sent unless the handler has written a response, so the caller can write its own status line.
+func (w *bufferedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if !w.wroteHeader {
+		w.committed = true
+	}
+	w.commit()
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the original http.ResponseWriter, so that http.ResponseController can reach it.
+func (w *bufferedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// commit sends the buffered header and body to the underlying http.ResponseWriter.
+func (w *bufferedWriter) commit() {
+	if w.committed {
+		return
+	}
+	w.committed = true
+
+	h := w.ResponseWriter.Header()
+	for key := range h {
+		delete(h, key)
+	}
+	for key, values := range w.header {
+		h[key] = values
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.buf.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+}
@@ -0,0 +1,63 @@
+package httpserver
+
+import (
+	"net/http"
+	"time"
+)
+
+// retryBufferLimit is the maximum size of a response body buffered by RetryMiddleware.
+// Larger responses are sent as they are written and never retried.
+const retryBufferLimit = 1 << 20 // 1 MB
+
+// RetryMiddleware retries GET and HEAD requests whose handler responds with a transient error status,
+// e.g. because of a flaky downstream dependency, up to maxRetries times, before the response is sent.
+// The delay before the n-th retry is backoff × 2^(n-1); retries stop early if the request context is done.
+// The retryable function reports whether a status is transient; if nil, 502, 503 and 504 are retried.
+//
+// The response of every attempt is buffered, so the client gets only the last one.
+// A response is committed and no longer retried once its body exceeds 1 MB, or the handler flushes it
+// or hijacks the connection. Requests with a body are not retried, since the body cannot be replayed.
+// The handler must be safe to run several times for the same request.
+func RetryMiddleware(maxRetries int, backoff time.Duration, retryable func(status int) bool) func(http.Handler) http.Handler {
+	if retryable == nil {
+		retryable = func(status int) bool {
+			return status == http.StatusBadGateway ||
+				status == http.StatusServiceUnavailable ||
+				status == http.StatusGatewayTimeout
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if maxRetries <= 0 || (r.Method != http.MethodGet && r.Method != http.MethodHead) || r.ContentLength != 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			initial := w.Header().Clone()
+			for attempt := 0; ; attempt++ {
+				rw := newBufferedWriter(w, initial.Clone(), retryBufferLimit)
+				next.ServeHTTP(rw, r)
+				if rw.committed {
+					return
+				}
+				if attempt == maxRetries || !retryable(rw.status) || !sleepContext(r, backoff<<attempt) {
+					rw.commit()
+					return
+				}
+			}
+		})
+	}
+}
+
+// sleepContext waits for the given duration. It reports false if the request context is done meanwhile.
+func sleepContext(r *http.Request, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
@@ -0,0 +1,133 @@
+package httpserver_test
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryMiddleware(t *testing.T) {
+	// flaky fails the first n attempts of every request with 503
+	flaky := func(n int32, attempts *atomic.Int32) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if attempts.Add(1) <= n {
+				w.Header().Set("X-Failed-Attempt", "true")
+				http.Error(w, "downstream unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("ok"))
+		})
+	}
+
+	t.Run("fails then succeeds", func(t *testing.T) {
+		var attempts atomic.Int32
+		handler := httpserver.RetryMiddleware(3, time.Millisecond, nil)(flaky(2, &attempts))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "ok", rec.Body.String())
+		require.Empty(t, rec.Header().Get("X-Failed-Attempt"))
+		require.EqualValues(t, 3, attempts.Load())
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		var attempts atomic.Int32
+		handler := httpserver.RetryMiddleware(2, time.Millisecond, nil)(flaky(10, &attempts))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		require.Equal(t, "downstream unavailable\n", rec.Body.String())
+		require.EqualValues(t, 3, attempts.Load())
+	})
+
+	t.Run("non-idempotent request", func(t *testing.T) {
+		var attempts atomic.Int32
+		handler := httpserver.RetryMiddleware(3, time.Millisecond, nil)(flaky(1, &attempts))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("data")))
+
+		require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		require.EqualValues(t, 1, attempts.Load())
+	})
+
+	t.Run("custom retryable status", func(t *testing.T) {
+		var attempts atomic.Int32
+		handler := httpserver.RetryMiddleware(3, time.Millisecond, func(status int) bool {
+			return status == http.StatusBadGateway
+		})(flaky(1, &attempts))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		require.EqualValues(t, 1, attempts.Load())
+	})
+
+	t.Run("flushed response is not retried", func(t *testing.T) {
+		var attempts atomic.Int32
+		handler := httpserver.RetryMiddleware(3, time.Millisecond, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("partial"))
+			http.NewResponseController(w).Flush()
+		}))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		require.Equal(t, "partial", rec.Body.String())
+		require.True(t, rec.Flushed)
+		require.EqualValues(t, 1, attempts.Load())
+	})
+}
+
+func TestBufferingMiddlewaresHijack(t *testing.T) {
+	middlewares := map[string]func(http.Handler) http.Handler{
+		"RetryMiddleware": httpserver.RetryMiddleware(2, time.Millisecond, nil),
+		"ETagMiddleware":  httpserver.ETagMiddleware(),
+	}
+
+	upgrade := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+		_ = rw.Flush()
+	})
+
+	for name, mw := range middlewares {
+		mw := mw
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(mw(upgrade))
+			defer srv.Close()
+
+			conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+			require.NoError(t, err)
+			defer conn.Close()
+			_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n"))
+			require.NoError(t, err)
+
+			// The handler's status line must be the first thing on the wire
+			line, err := bufio.NewReader(conn).ReadString('\n')
+			require.NoError(t, err)
+			require.Equal(t, "HTTP/1.1 101 Switching Protocols\r\n", line)
+		})
+	}
+}
@@ -2,71 +2,125 @@ package httpserver
 
 import (
 	"embed"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
+	"slices"
 	"strings"
 	"time"
 )
 
+// now returns the current time. It is a variable so that tests can make caching headers deterministic.
+var now = time.Now
+
 // StaticHandler creates a new http.HandlerFunc that serves static files from the specified root directory.
 // It does not allow directory listings and optionally supports caching of the served files.
+// Directory requests, including the public path itself, follow the http.FileServer conventions:
+// "/static" is redirected to "/static/", which serves "index.html" of the directory if it exists.
+// Dotfiles, e.g. ".env", are not served by default, see WithDenyDotfiles.
 //
 // Parameters:
 // - publicPath: The URL path prefix from which the static files will be served.
 // - root: The http.FileSystem representing the root directory from which files will be served.
 // - cacheTTL: The duration for which the client should cache the served files.
+// - opts: Optional static handler options.
 //
 // Returns:
 // An http.HandlerFunc that serves static files with optional caching.
-func StaticHandler(publicPath string, root http.FileSystem, cacheTTL time.Duration) http.HandlerFunc {
-	return serveStaticHandlerFunc(publicPath, root, cacheTTL)
+func StaticHandler(publicPath string, root http.FileSystem, cacheTTL time.Duration, opts ...staticOption) http.HandlerFunc {
+	return serveStaticHandlerFunc(publicPath, root, newStaticConfig(cacheTTL, opts...))
+}
+
+// SPAHandler creates a new http.HandlerFunc that serves a single-page application from the specified root directory.
+// It behaves like StaticHandler, but requests for files that do not exist are answered with the index file,
+// so that client-side routes are handled by the application.
+//
+// Parameters:
+// - publicPath: The URL path prefix from which the application will be served.
+// - root: The http.FileSystem representing the root directory from which files will be served.
+// - index: The path of the index file relative to the root, e.g. "/index.html".
+// - cacheTTL: The duration for which the client should cache the served files.
+// - opts: Optional static handler options.
+//
+// Returns:
+// An http.HandlerFunc that serves static files with a fallback to the index file.
+func SPAHandler(publicPath string, root http.FileSystem, index string, cacheTTL time.Duration, opts ...staticOption) http.HandlerFunc {
+	cfg := newStaticConfig(cacheTTL, opts...)
+	cfg.fallback = "/" + strings.TrimLeft(index, "/")
+	return serveStaticHandlerFunc(publicPath, root, cfg)
 }
 
 // EmbeddedStaticHandler creates a new http.HandlerFunc that serves static files from an embedded file system.
 // It uses the embed.FS type to serve files from the specified directory.
+// Embedded files have no modification time, so they are served with an ETag derived from their content
+// and without Last-Modified, which keeps conditional and range requests, including If-Range, reliable.
 //
 // Parameters:
 // - fs: The embed.FS representing the embedded file system.
 // - cacheTTL: The duration for which the client should cache the served files.
+// - opts: Optional static handler options.
 //
 // Returns:
 // An http.HandlerFunc that serves static files with optional caching.
-func EmbeddedStaticHandler(fs embed.FS, cacheTTL time.Duration) http.HandlerFunc {
-	return serveStaticHandlerFunc("", http.FS(fs), cacheTTL)
+func EmbeddedStaticHandler(fs embed.FS, cacheTTL time.Duration, opts ...staticOption) http.HandlerFunc {
+	return serveStaticHandlerFunc("", http.FS(fs), newStaticConfig(cacheTTL, opts...))
 }
 
 // serveFile serves a single file through HTTP with optional caching.
 // It sets appropriate headers for caching based on the cacheTTL parameter.
 // If cacheTTL is 0, caching is disabled.
+// Files without a modification time, e.g. embedded ones, are validated by a hash of their content.
 //
 // Parameters:
 // - w: The http.ResponseWriter to write the response to.
 // - r: The *http.Request representing the client's request.
+// - fsPath: The path of the file relative to the static root.
 // - file: The http.File representing the file to serve.
 // - info: The os.FileInfo containing metadata about the file.
-// - cacheTTL: The duration for which the file should be cached by the client.
-func serveFile(w http.ResponseWriter, r *http.Request, file http.File, info os.FileInfo, cacheTTL time.Duration) {
+// - cfg: The static handler configuration, providing the cache TTL.
+func serveFile(w http.ResponseWriter, r *http.Request, fsPath string, file http.File, info os.FileInfo, cfg *staticConfig) {
+	cacheTTL := cfg.cacheTTL
+
+	// Files without a modification time, e.g. embedded ones, get a content hash ETag,
+	// so that conditional and If-Range requests have a reliable validator
+	var etag string
+	if info.ModTime().IsZero() {
+		var err error
+		if etag, err = cfg.contentETags.get(fsPath, file); err != nil {
+			renderError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
 	if cacheTTL == 0 {
 		// No caching
+		if etag != "" {
+			w.Header().Set("ETag", etag)
+		}
 		http.ServeContent(w, r, info.Name(), info.ModTime(), file)
 		return
 	}
 
-	// Generate ETag using file info
-	etag := fmt.Sprintf(`"%x-%x"`, info.ModTime().Unix(), info.Size())
-	lastModified := info.ModTime().UTC().Format(http.TimeFormat)
+	// Generate ETag using file info, unless the content hash is used
+	if etag == "" {
+		etag = fmt.Sprintf(`"%x-%x"`, info.ModTime().Unix(), info.Size())
+		w.Header().Set("Last-Modified", info.ModTime().UTC().Format(http.TimeFormat))
+	}
 
 	// Set headers for caching
 	w.Header().Set("ETag", etag)
-	w.Header().Set("Last-Modified", lastModified)
 	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(cacheTTL.Seconds())))
-	w.Header().Set("Expires", time.Now().Add(cacheTTL).UTC().Format(http.TimeFormat))
+	w.Header().Set("Expires", now().Add(cacheTTL).UTC().Format(http.TimeFormat))
 	w.Header().Set("Pragma", "cache")
 
 	// Check if file hasn't been modified since the last request
 	if match := r.Header.Get("If-None-Match"); match != "" {
-		if strings.Contains(match, etag) {
+		if etagMatches(match, etag) {
 			w.WriteHeader(http.StatusNotModified)
 			return
 		}
@@ -74,7 +128,7 @@ func serveFile(w http.ResponseWriter, r *http.Request, file http.File, info os.F
 
 	// Check if file has been modified since the last request based on Last-Modified header
 	ifModifiedSince := r.Header.Get("If-Modified-Since")
-	if ifModifiedSince != "" {
+	if ifModifiedSince != "" && !info.ModTime().IsZero() {
 		if t, err := time.Parse(http.TimeFormat,
 			ifModifiedSince); err == nil && info.ModTime().Before(t.Add(1*time.Second)) {
 			w.WriteHeader(http.StatusNotModified)
@@ -86,26 +140,141 @@ func serveFile(w http.ResponseWriter, r *http.Request, file http.File, info os.F
 	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
 }
 
+// serveFileNoStore serves a single file through HTTP and forbids caching it anywhere.
+// No validators (ETag, Last-Modified) are sent, so conditional requests always get the full content.
+func serveFileNoStore(w http.ResponseWriter, r *http.Request, name string, content io.ReadSeeker) {
+	w.Header().Set("Cache-Control", "no-store, no-cache")
+	w.Header().Set("Pragma", "no-cache")
+
+	// Drop conditional headers so that http.ServeContent never responds with 304 Not Modified
+	r = r.Clone(r.Context())
+	r.Header.Del("If-None-Match")
+	r.Header.Del("If-Modified-Since")
+
+	// Zero modtime prevents http.ServeContent from setting Last-Modified
+	http.ServeContent(w, r, name, time.Time{}, content)
+}
+
+// indexFile is the name of the file served for directory requests.
+const indexFile = "index.html"
+
+// redirectToDirectory redirects the request to the same path with a trailing slash.
+// The redirect is relative, so it also works behind a prefix-stripping router.
+func redirectToDirectory(w http.ResponseWriter, r *http.Request) {
+	target := path.Base(r.URL.Path) + "/"
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	w.Header().Set("Location", target)
+	w.WriteHeader(http.StatusMovedPermanently)
+}
+
+// redirectToCDN redirects the request for a missing file to the same path under the CDN base URL,
+// preserving the query string, e.g. a cache-busting version.
+func redirectToCDN(w http.ResponseWriter, r *http.Request, baseURL, fsPath string) {
+	target := baseURL + (&url.URL{Path: fsPath}).EscapedPath()
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// openFile opens the file at the given path of the file system and returns it along with its info.
+// Directories are not served as files, so errIsDirectory is returned for them.
+func openFile(root http.FileSystem, name string) (http.File, os.FileInfo, error) {
+	file, err := root.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, nil, err
+	}
+
+	if info.IsDir() {
+		_ = file.Close()
+		return nil, nil, errIsDirectory
+	}
+
+	return file, info, nil
+}
+
 // serveStaticHandlerFunc creates and returns a http.HandlerFunc that serves static files from a specified root directory.
 // It does not allow directory listings and optionally supports caching of the served files.
 //
 // Parameters:
 // - publicPath: The URL path prefix from which the static files will be served.
 // - root: The http.FileSystem representing the root directory from which files will be served.
-// - cacheTTL: The duration for which the client should cache the served files.
+// - cfg: The static handler configuration.
 //
 // Returns:
 // An http.HandlerFunc that serves static files with optional caching.
-func serveStaticHandlerFunc(publicPath string, root http.FileSystem, cacheTTL time.Duration) http.HandlerFunc {
+func serveStaticHandlerFunc(publicPath string, root http.FileSystem, cfg *staticConfig) http.HandlerFunc {
 	publicPath = strings.TrimRight(publicPath, "/")
+	allow := strings.Join(cfg.allowedMethods, ", ")
 	return func(w http.ResponseWriter, r *http.Request) {
-		fsPath := strings.TrimPrefix(r.URL.Path, publicPath)
-		file, err := root.Open(fsPath)
+		if !slices.Contains(cfg.allowedMethods, r.Method) {
+			w.Header().Set("Allow", allow)
+			renderError(w, r, http.StatusMethodNotAllowed, statusError(http.StatusMethodNotAllowed))
+			return
+		}
+
+		fsPath := path.Clean("/" + strings.TrimPrefix(r.URL.Path, publicPath))
+		if !cfg.allowDotfiles && hasDotSegment(fsPath) {
+			// Do not confirm the existence of the file
+			renderError(w, r, http.StatusNotFound, statusError(http.StatusNotFound))
+			return
+		}
+		restricted := cfg.restricted != nil && cfg.restricted.matches(fsPath)
+		if restricted && !addrInPrefixes(ClientIP(r, cfg.restricted.trustedProxies), cfg.restricted.allow) {
+			// Do not confirm the existence of the file
+			renderError(w, r, http.StatusNotFound, statusError(http.StatusNotFound))
+			return
+		}
+		file, info, err := openFile(root, fsPath)
+		if errors.Is(err, errIsDirectory) {
+			// Directories, including the mount root, behave like in http.FileServer:
+			// redirect to the path with a trailing slash, then serve the index file if there is one.
+			// Without the redirect, a directory path lacking the trailing slash is not found.
+			if !strings.HasSuffix(r.URL.Path, "/") {
+				if !cfg.noDirRedirect {
+					redirectToDirectory(w, r)
+					return
+				}
+			} else {
+				indexPath := path.Join(fsPath, indexFile)
+				if idxFile, idxInfo, idxErr := openFile(root, indexPath); idxErr == nil {
+					file, info, fsPath, err = idxFile, idxInfo, indexPath, nil
+				} else if cfg.dirListing {
+					serveDirectoryListing(w, r, root, fsPath, !cfg.allowDotfiles)
+					return
+				}
+			}
+		}
+		if errors.Is(err, fs.ErrNotExist) && cfg.cdnFallback != "" {
+			// File not found locally, redirect to the CDN
+			redirectToCDN(w, r, cfg.cdnFallback, fsPath)
+			return
+		}
+		if err != nil && cfg.fallback != "" {
+			// File not found, serve the fallback file instead
+			fsPath = cfg.fallback
+			file, info, err = openFile(root, fsPath)
+		}
 		if err != nil {
-			// File not found
-			http.NotFound(w, r)
+			// File not found or path is a directory
+			renderError(w, r, http.StatusNotFound, statusError(http.StatusNotFound))
 			return
 		}
+		if cfg.imageAlts && hasImageAlternatives(fsPath) {
+			w.Header().Add("Vary", "Accept")
+			if altFile, altInfo, altPath, ok := openImageAlternative(root, fsPath, r.Header.Get("Accept")); ok {
+				_ = file.Close()
+				file, info, fsPath = altFile, altInfo, altPath
+			}
+		}
 		defer func(file http.File) {
 			if err := file.Close(); err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -113,20 +282,31 @@ func serveStaticHandlerFunc(publicPath string, root http.FileSystem, cacheTTL ti
 			}
 		}(file)
 
-		info, err := file.Stat()
-		if err != nil {
-			// Error getting file info
-			http.NotFound(w, r)
+		if cfg.integrity != nil {
+			setPreloadLink(w, r, cfg.integrity, fsPath)
+		}
+		if ct, ok := cfg.contentTypeFor(fsPath); ok {
+			w.Header().Set("Content-Type", ct)
+		}
+
+		if cfg.cspNonce != nil && isHTMLFile(info.Name()) {
+			// HTML with a per-request nonce, never cache
+			serveHTMLWithNonce(w, r, file, info, cfg.cspNonce)
 			return
 		}
 
-		if info.IsDir() {
-			// Path is a directory, return 404
-			http.NotFound(w, r)
+		if cfg.streamBuffers != nil && r.Header.Get("Range") == "" {
+			// Full file, copy it with a pooled buffer
+			w = &streamWriter{ResponseWriter: w, buffers: cfg.streamBuffers}
+		}
+
+		if cfg.liveReload || restricted || (cfg.noStore != nil && cfg.noStore(fsPath)) {
+			// Sensitive or restricted file, or development mode, never cache
+			serveFileNoStore(w, r, info.Name(), file)
 			return
 		}
 
 		// Serve file with caching
-		serveFile(w, r, file, info, cacheTTL)
+		serveFile(w, r, fsPath, file, info, cfg)
 	}
 }
@@ -0,0 +1,61 @@
+package httpserver
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// cspNonceConfig holds the configuration of the CSP nonce injection.
+type cspNonceConfig struct {
+	placeholder string
+	policy      string
+}
+
+// isHTMLFile reports whether the file name has an HTML extension.
+func isHTMLFile(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".html", ".htm":
+		return true
+	}
+	return false
+}
+
+// generateNonce returns a random base64-encoded nonce suitable for a Content-Security-Policy.
+func generateNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// serveHTMLWithNonce serves an HTML file with a freshly generated nonce
+// injected into both the body and the Content-Security-Policy header.
+func serveHTMLWithNonce(w http.ResponseWriter, r *http.Request, file http.File, info os.FileInfo, cfg *cspNonceConfig) {
+	nonce, err := generateNonce()
+	if err != nil {
+		renderError(w, r, http.StatusInternalServerError, statusError(http.StatusInternalServerError))
+		return
+	}
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		renderError(w, r, http.StatusInternalServerError, statusError(http.StatusInternalServerError))
+		return
+	}
+	policy := cfg.policy
+	if cfg.placeholder != "" {
+		content = bytes.ReplaceAll(content, []byte(cfg.placeholder), []byte(nonce))
+		policy = strings.ReplaceAll(policy, cfg.placeholder, nonce)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", policy)
+	serveFileNoStore(w, r, info.Name(), bytes.NewReader(content))
+}
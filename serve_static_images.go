@@ -0,0 +1,43 @@
+package httpserver
+
+import (
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// imageAlternatives lists the modern image formats in order of preference.
+var imageAlternatives = []struct {
+	mediaType string
+	ext       string
+}{
+	{mediaType: "image/avif", ext: ".avif"},
+	{mediaType: "image/webp", ext: ".webp"},
+}
+
+// hasImageAlternatives reports whether the file is an image which may have modern format alternatives.
+func hasImageAlternatives(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".jpg", ".jpeg", ".png", ".gif":
+		return true
+	}
+	return false
+}
+
+// openImageAlternative opens the most preferred modern format sibling of the image
+// which is accepted by the client according to the Accept header value.
+// It returns false if there is no such alternative.
+func openImageAlternative(root http.FileSystem, name, accept string) (http.File, os.FileInfo, string, bool) {
+	base := strings.TrimSuffix(name, path.Ext(name))
+	for _, alt := range imageAlternatives {
+		if !acceptsMediaType(accept, alt.mediaType) {
+			continue
+		}
+		altName := base + alt.ext
+		if file, info, err := openFile(root, altName); err == nil {
+			return file, info, altName, true
+		}
+	}
+	return nil, nil, "", false
+}
@@ -0,0 +1,107 @@
+package httpserver
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// IntegrityManifest maps static file paths to their Subresource Integrity values, e.g. "sha384-...".
+// Paths are relative to the static root and start with a slash, e.g. "/js/app.js".
+// The manifest is computed or loaded once at startup and is safe for concurrent use.
+type IntegrityManifest struct {
+	hashes map[string]string
+}
+
+// NewIntegrityManifest computes the sha384 integrity values of all the files in the file system.
+// Use os.DirFS for files on disk, or the embed.FS directly.
+func NewIntegrityManifest(fsys fs.FS) (*IntegrityManifest, error) {
+	hashes := make(map[string]string)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		f, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		h := sha512.New384()
+		if _, err := io.Copy(h, f); err != nil {
+			return err
+		}
+		hashes["/"+name] = "sha384-" + base64.StdEncoding.EncodeToString(h.Sum(nil))
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Join(ErrInvalidStaticFS, err)
+	}
+	return &IntegrityManifest{hashes: hashes}, nil
+}
+
+// LoadIntegrityManifest reads a precomputed manifest, e.g. generated at build time,
+// as a JSON object mapping file paths to integrity values.
+func LoadIntegrityManifest(r io.Reader) (*IntegrityManifest, error) {
+	var raw map[string]string
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIntegrityManifest, err)
+	}
+	hashes := make(map[string]string, len(raw))
+	for name, value := range raw {
+		hashes["/"+strings.TrimPrefix(name, "/")] = value
+	}
+	return &IntegrityManifest{hashes: hashes}, nil
+}
+
+// Manifest returns a copy of the file path to integrity value mapping,
+// e.g. to generate integrity attributes in templates.
+func (m *IntegrityManifest) Manifest() map[string]string {
+	result := make(map[string]string, len(m.hashes))
+	for name, value := range m.hashes {
+		result[name] = value
+	}
+	return result
+}
+
+// Integrity returns the integrity value of the file at the given path.
+func (m *IntegrityManifest) Integrity(name string) (string, bool) {
+	value, ok := m.hashes["/"+strings.TrimPrefix(name, "/")]
+	return value, ok
+}
+
+// preloadDestinations maps file extensions to the "as" attribute of preload links.
+var preloadDestinations = map[string]string{
+	".js":    "script",
+	".mjs":   "script",
+	".css":   "style",
+	".woff":  "font",
+	".woff2": "font",
+}
+
+// setPreloadLink adds the preload Link header with the integrity value of the served file,
+// if the manifest has it and the file type can be preloaded.
+func setPreloadLink(w http.ResponseWriter, r *http.Request, m *IntegrityManifest, fsPath string) {
+	as, ok := preloadDestinations[strings.ToLower(path.Ext(fsPath))]
+	if !ok {
+		return
+	}
+	integrity, ok := m.Integrity(fsPath)
+	if !ok {
+		return
+	}
+
+	link := fmt.Sprintf("<%s>; rel=preload; as=%s; integrity=%q", r.URL.EscapedPath(), as, integrity)
+	if as == "font" {
+		// Fonts are always fetched in CORS mode
+		link += "; crossorigin"
+	}
+	w.Header().Add("Link", link)
+}
@@ -0,0 +1,139 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeFileCacheHeaders(t *testing.T) {
+	fixedNow := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return fixedNow }
+	t.Cleanup(func() { now = time.Now })
+
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	root := http.FS(fstest.MapFS{
+		"app.js": {Data: []byte("console.log('app')"), ModTime: modTime},
+	})
+	handler := StaticHandler("/static", root, time.Hour)
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/static/app.js", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "Sat, 01 Jun 2024 13:00:00 GMT", rec.Header().Get("Expires"))
+	require.Equal(t, "Mon, 01 Jan 2024 00:00:00 GMT", rec.Header().Get("Last-Modified"))
+	require.Equal(t, "public, max-age=3600", rec.Header().Get("Cache-Control"))
+	require.Equal(t, `"65920080-12"`, rec.Header().Get("ETag"))
+}
+
+func TestETagMatches(t *testing.T) {
+	etag := `"65920080-12"`
+
+	tests := []struct {
+		header   string
+		expected bool
+	}{
+		{header: `"65920080-12"`, expected: true},
+		{header: `W/"65920080-12"`, expected: true},
+		{header: `*`, expected: true},
+		{header: ` * `, expected: true},
+		{header: `*garbage`, expected: false},
+		{header: `*"65920080-12"`, expected: false},
+		{header: `"abc", "65920080-12"`, expected: true},
+		{header: `"abc",W/"65920080-12" ,"def"`, expected: true},
+		{header: `"65920080-123"`, expected: false},
+		{header: `"x65920080-12"`, expected: false},
+		{header: `"abc", "def"`, expected: false},
+		{header: `65920080-12`, expected: false},
+		{header: `"65920080-12`, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			require.Equal(t, tt.expected, etagMatches(tt.header, etag))
+		})
+	}
+}
+
+func TestServeFileIfNoneMatch(t *testing.T) {
+	root := http.FS(fstest.MapFS{
+		"app.js": {Data: []byte("console.log('app')"), ModTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	})
+	handler := StaticHandler("/static", root, time.Hour)
+
+	for header, expected := range map[string]int{
+		`"other", W/"65920080-12"`: http.StatusNotModified,
+		`"65920080-1"`:             http.StatusOK,
+		`"65920080-12345"`:         http.StatusOK,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/static/app.js", nil)
+		req.Header.Set("If-None-Match", header)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		require.Equal(t, expected, rec.Code, header)
+	}
+}
+
+// countingFS counts the bytes read from the files of the wrapped file system.
+type countingFS struct {
+	http.FileSystem
+	read *atomic.Int64
+}
+
+func (c countingFS) Open(name string) (http.File, error) {
+	f, err := c.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return countingFile{File: f, read: c.read}, nil
+}
+
+type countingFile struct {
+	http.File
+	read *atomic.Int64
+}
+
+func (c countingFile) Read(p []byte) (int, error) {
+	n, err := c.File.Read(p)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+func TestServeFileContentETagMemoized(t *testing.T) {
+	var read atomic.Int64
+	size := 64 << 10
+	root := countingFS{
+		// No modification time, like embedded files
+		FileSystem: http.FS(fstest.MapFS{"app.js": {Data: []byte(strings.Repeat("a", size))}}),
+		read:       &read,
+	}
+	handler := StaticHandler("/static", root, time.Hour)
+
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/static/app.js", nil)
+		req.Header.Set("Range", "bytes=0-0")
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	// The first request hashes the whole file
+	rec := get()
+	require.Equal(t, http.StatusPartialContent, rec.Code)
+	etag := rec.Header().Get("ETag")
+	require.GreaterOrEqual(t, read.Load(), int64(size))
+
+	// Later requests reuse the ETag and read only what they serve
+	read.Store(0)
+	rec = get()
+	require.Equal(t, http.StatusPartialContent, rec.Code)
+	require.Equal(t, etag, rec.Header().Get("ETag"))
+	require.Less(t, read.Load(), int64(size))
+}
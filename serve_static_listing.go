@@ -0,0 +1,95 @@
+package httpserver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+)
+
+// errIsDirectory is returned by openFile for directories, which are not served as files.
+var errIsDirectory = errors.New("path is a directory")
+
+// serveDirectoryListing renders an HTML listing of the directory entries, omitting dotfiles if hideDotfiles is set.
+// The generated page participates in the compression negotiation like any other response.
+func serveDirectoryListing(w http.ResponseWriter, r *http.Request, root http.FileSystem, name string, hideDotfiles bool) {
+	dir, err := root.Open(name)
+	if err != nil {
+		renderError(w, r, http.StatusNotFound, statusError(http.StatusNotFound))
+		return
+	}
+	defer dir.Close()
+
+	entries, err := dir.Readdir(-1)
+	if err != nil {
+		renderError(w, r, http.StatusInternalServerError, statusError(http.StatusInternalServerError))
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var buf bytes.Buffer
+	title := html.EscapeString(r.URL.Path)
+	fmt.Fprintf(&buf, "<!doctype html>\n<html>\n<head><meta charset=\"utf-8\"><title>%s</title></head>\n<body>\n<h1>%s</h1>\n<ul>\n", title, title)
+	for _, e := range entries {
+		if hideDotfiles && isDotfile(e.Name()) {
+			continue
+		}
+		entryName := e.Name()
+		if e.IsDir() {
+			entryName += "/"
+		}
+		href := (&url.URL{Path: path.Join(r.URL.Path, e.Name())}).String()
+		if e.IsDir() {
+			href += "/"
+		}
+		fmt.Fprintf(&buf, "<li><a href=\"%s\">%s</a></li>\n", html.EscapeString(href), html.EscapeString(entryName))
+	}
+	buf.WriteString("</ul>\n</body>\n</html>\n")
+
+	w.Header().Set("Cache-Control", "no-cache")
+	writeGenerated(w, r, "text/html; charset=utf-8", buf.Bytes(), DefaultCompressionOptions())
+}
+
+// writeGenerated writes a response generated in memory, compressing it with gzip
+// if the client accepts it and the content is compressible according to the options.
+// It responds with 406 Not Acceptable if the client accepts neither gzip nor identity.
+func writeGenerated(w http.ResponseWriter, r *http.Request, contentType string, body []byte, opts CompressionOptions) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	offers := []string{}
+	if len(body) >= opts.MinSize && opts.compressible(contentType) && !opts.excluded(r.URL.Path) {
+		offers = append(offers, encodingGzip)
+	}
+
+	encoding, ok := negotiateEncoding(r.Header.Get("Accept-Encoding"), offers)
+	if !ok {
+		renderError(w, r, http.StatusNotAcceptable, statusError(http.StatusNotAcceptable))
+		return
+	}
+
+	if encoding == encodingGzip {
+		var buf bytes.Buffer
+		zw, err := gzip.NewWriterLevel(&buf, opts.Level)
+		if err == nil {
+			_, _ = zw.Write(body)
+			err = zw.Close()
+		}
+		if err == nil {
+			body = buf.Bytes()
+			w.Header().Set("Content-Encoding", encodingGzip)
+		}
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(body)
+	}
+}
@@ -0,0 +1,266 @@
+package httpserver
+
+import (
+	"io"
+	"net/http"
+	"net/netip"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type staticOption func(*staticConfig)
+
+// staticConfig holds the configuration of the static file handlers.
+type staticConfig struct {
+	cacheTTL       time.Duration
+	allowedMethods []string
+	noStore        func(path string) bool
+	fallback       string
+	cspNonce       *cspNonceConfig
+	imageAlts      bool
+	dirListing     bool
+	streamBuffers  *sync.Pool
+	integrity      *IntegrityManifest
+	cdnFallback    string
+	liveReload     bool
+	restricted     *restrictedExtensions
+	contentTypes   map[string]string
+	noDirRedirect  bool
+	allowDotfiles  bool
+	contentETags   *contentETags
+}
+
+// contentETags memoizes the content hash ETags of the files without a modification time, e.g. embedded ones.
+// Such files are immutable, so each of them is hashed once per handler rather than on every request.
+type contentETags struct {
+	etags sync.Map // file path -> ETag
+}
+
+// get returns the ETag of the file at the given path, hashing its content on the first call.
+func (c *contentETags) get(fsPath string, file io.ReadSeeker) (string, error) {
+	if etag, ok := c.etags.Load(fsPath); ok {
+		return etag.(string), nil
+	}
+	etag, err := readerETag(file)
+	if err != nil {
+		return "", err
+	}
+	c.etags.Store(fsPath, etag)
+	return etag, nil
+}
+
+// restrictedExtensions is the set of file extensions served only to the allowed client addresses.
+type restrictedExtensions struct {
+	exts           []string
+	allow          []netip.Prefix
+	trustedProxies []netip.Prefix
+}
+
+// matches reports whether the file path has one of the restricted extensions.
+func (re *restrictedExtensions) matches(fsPath string) bool {
+	return slices.Contains(re.exts, strings.ToLower(path.Ext(fsPath)))
+}
+
+// newStaticConfig creates the static handler configuration with the given cache TTL
+// and applies the options in order.
+func newStaticConfig(cacheTTL time.Duration, opts ...staticOption) *staticConfig {
+	cfg := &staticConfig{
+		cacheTTL:       cacheTTL,
+		allowedMethods: []string{http.MethodGet, http.MethodHead},
+		contentTypes:   newContentTypes(),
+		contentETags:   &contentETags{},
+	}
+	for _, o := range opts {
+		o(cfg)
+	}
+	return cfg
+}
+
+// WithAllowedMethods sets the HTTP methods the static handler responds to.
+// Requests with other methods get 405 Method Not Allowed with the Allow header listing the allowed methods.
+// By default only GET and HEAD are allowed.
+func WithAllowedMethods(methods ...string) staticOption {
+	return func(cfg *staticConfig) {
+		cfg.allowedMethods = make([]string, 0, len(methods))
+		for _, m := range methods {
+			cfg.allowedMethods = append(cfg.allowedMethods, strings.ToUpper(m))
+		}
+	}
+}
+
+// WithNoStore sets a predicate for files which must never be cached, e.g. per-user generated configs.
+// The predicate receives the file path relative to the static root, starting with a slash.
+// Matching files are served with "Cache-Control: no-store, no-cache" and without ETag and Last-Modified headers,
+// regardless of the cache TTL, and conditional requests for them never result in 304 Not Modified.
+func WithNoStore(fn func(path string) bool) staticOption {
+	return func(cfg *staticConfig) {
+		cfg.noStore = fn
+	}
+}
+
+// WithLiveReload disables caching of all the served files: they are sent with "Cache-Control: no-store, no-cache"
+// and without ETag and Last-Modified headers, regardless of the cache TTL, and conditional requests never result
+// in 304 Not Modified. This way browser caches never mask edits of files served from a live directory,
+// e.g. os.DirFS, which picks up changes without a restart. An embedded FS is static and still needs a rebuild.
+//
+// It is a development-only option: never enable it in production, since every request transfers the full file.
+func WithLiveReload() staticOption {
+	return func(cfg *staticConfig) {
+		cfg.liveReload = true
+	}
+}
+
+// WithRestrictedExtensions makes files with the given extensions, e.g. ".map" for JavaScript source maps,
+// available only to clients whose address belongs to one of the allowed prefixes, e.g. the office network.
+// Other clients get 404 Not Found, as if the file did not exist, so its existence is not confirmed;
+// the index fallback of SPAHandler and the CDN fallback do not apply to them.
+// Extensions are matched case-insensitively, with or without the leading dot.
+//
+// The client address is resolved with ClientIP: behind a reverse proxy, pass its prefixes as trustedProxies,
+// so that the forwarding headers it sets are used; without them, the client is the immediate peer of the connection.
+// Restricted files are served without caching, so that shared caches never hand them to other clients.
+func WithRestrictedExtensions(exts []string, allow []netip.Prefix, trustedProxies ...netip.Prefix) staticOption {
+	return func(cfg *staticConfig) {
+		re := &restrictedExtensions{allow: allow, trustedProxies: trustedProxies}
+		for _, ext := range exts {
+			re.exts = append(re.exts, "."+strings.ToLower(strings.TrimPrefix(ext, ".")))
+		}
+		cfg.restricted = re
+	}
+}
+
+// WithContentTypes sets the content types of files by extension, e.g. {".webmanifest": "application/manifest+json"},
+// overriding both the defaults and the type otherwise resolved from the extension or sniffed from the content.
+// Extensions are matched case-insensitively, with or without the leading dot; an empty content type
+// removes the mapping. By default Markdown (.md, .markdown), reStructuredText (.rst), TOML (.toml)
+// and Gemini (.gmi) files are served as "text/plain; charset=utf-8", so that browsers render them inline.
+func WithContentTypes(types map[string]string) staticOption {
+	return func(cfg *staticConfig) {
+		for ext, ct := range types {
+			ext = "." + strings.ToLower(strings.TrimPrefix(ext, "."))
+			if ct == "" {
+				delete(cfg.contentTypes, ext)
+				continue
+			}
+			cfg.contentTypes[ext] = ct
+		}
+	}
+}
+
+// WithDirectoryRedirect sets whether requests for a directory without the trailing slash, e.g. "/static/docs",
+// are redirected with 301 Moved Permanently to the path with it, e.g. "/static/docs/", preserving the query string,
+// like http.FileServer does, so that relative links in the served index file resolve correctly.
+// The redirect is enabled by default. When disabled, such requests are handled as for a missing file:
+// they get 404 Not Found, or the index fallback of SPAHandler, which lets the client-side router own these paths.
+func WithDirectoryRedirect(enabled bool) staticOption {
+	return func(cfg *staticConfig) {
+		cfg.noDirRedirect = !enabled
+	}
+}
+
+// WithDenyDotfiles sets whether files and directories whose name starts with a dot, e.g. ".env", ".git/config"
+// or ".htpasswd", are hidden: requests for paths with such a segment get 404 Not Found, as if the file did not exist,
+// and the directory listing omits them. The ".well-known" directory is always served, since it holds public
+// metadata, e.g. ACME challenges. Dotfiles are denied by default; disable it only for a file system
+// without secrets.
+func WithDenyDotfiles(deny bool) staticOption {
+	return func(cfg *staticConfig) {
+		cfg.allowDotfiles = !deny
+	}
+}
+
+// hasDotSegment reports whether any segment of the cleaned file path starts with a dot, except ".well-known".
+func hasDotSegment(fsPath string) bool {
+	for _, segment := range strings.Split(fsPath, "/") {
+		if isDotfile(segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDotfile reports whether the file name starts with a dot, except ".well-known".
+func isDotfile(name string) bool {
+	return strings.HasPrefix(name, ".") && name != ".well-known"
+}
+
+// WithCSPNonce enables injection of a per-request Content-Security-Policy nonce into served HTML files.
+// For every HTML response a new random nonce is generated and each occurrence of the placeholder
+// in both the file content and the policy is replaced with it, e.g.:
+//
+//	WithCSPNonce("{{nonce}}", "script-src 'nonce-{{nonce}}'")
+//
+// with `<script nonce="{{nonce}}">` in index.html.
+// The resulting policy is sent in the Content-Security-Policy header.
+// Since the body differs on every request, such responses are never cached and never result in 304 Not Modified.
+func WithCSPNonce(placeholder, policy string) staticOption {
+	return func(cfg *staticConfig) {
+		cfg.cspNonce = &cspNonceConfig{
+			placeholder: placeholder,
+			policy:      policy,
+		}
+	}
+}
+
+// WithImageAlternatives enables serving of modern image formats in place of the requested JPEG, PNG or GIF file.
+// If the client's Accept header includes "image/avif" or "image/webp" and a sibling file with the same name
+// and the matching extension exists (e.g. "photo.avif" next to "photo.jpg"), the sibling is served instead.
+// AVIF is preferred over WebP. If no alternative exists or the client does not support it,
+// the requested file is served. Responses for such images carry "Vary: Accept".
+func WithImageAlternatives() staticOption {
+	return func(cfg *staticConfig) {
+		cfg.imageAlts = true
+	}
+}
+
+// WithDirectoryListing enables HTML listings for directory requests, which get 404 Not Found by default.
+// The listing is gzip-compressed when the client supports it, like any other compressible response.
+func WithDirectoryListing() staticOption {
+	return func(cfg *staticConfig) {
+		cfg.dirListing = true
+	}
+}
+
+// WithStreamBufferSize sets the size of the buffer used to copy full files to the response.
+// Buffers are pooled and reused across requests, which reduces GC pressure under high concurrency.
+// Files are always streamed, never read into memory as a whole; the option only tunes the copy throughput.
+// Range requests are served as usual.
+//
+// Note that the default copy path may use sendfile for files on disk, which this option bypasses,
+// so benchmark your workload before enabling it. A non-positive size keeps the default behavior.
+func WithStreamBufferSize(n int) staticOption {
+	return func(cfg *staticConfig) {
+		if n <= 0 {
+			cfg.streamBuffers = nil
+			return
+		}
+		cfg.streamBuffers = newBufferPool(n)
+	}
+}
+
+// WithIntegrity makes the static handler send a preload Link header with the Subresource Integrity value
+// for the scripts, stylesheets and fonts listed in the manifest, e.g.:
+//
+//	Link: </static/app.js>; rel=preload; as=script; integrity="sha384-..."
+//
+// The same manifest exposes the values to templates via Manifest.
+func WithIntegrity(m *IntegrityManifest) staticOption {
+	return func(cfg *staticConfig) {
+		cfg.integrity = m
+	}
+}
+
+// WithCDNFallback makes the static handler redirect requests for files which do not exist locally
+// to the CDN with 302 Found, instead of responding with 404 Not Found, e.g. during a gradual migration of assets.
+// The target is the base URL followed by the file path relative to the static root and the query string,
+// so "/static/img/logo.png?v=2" mounted at "/static" redirects to "https://cdn.example.com/img/logo.png?v=2"
+// with the base URL "https://cdn.example.com". Other errors, e.g. directories, are not redirected.
+// The CDN fallback takes precedence over the index fallback of SPAHandler.
+func WithCDNFallback(baseURL string) staticOption {
+	return func(cfg *staticConfig) {
+		cfg.cdnFallback = strings.TrimRight(baseURL, "/")
+	}
+}
@@ -0,0 +1,39 @@
+package httpserver
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// streamWriter copies the file content to the response with a buffer from the pool,
+// instead of the default copy path of the underlying http.ResponseWriter.
+type streamWriter struct {
+	http.ResponseWriter
+	buffers *sync.Pool
+}
+
+// ReadFrom copies the content from the reader using a pooled buffer.
+// http.ServeContent writes the body through io.Copy, which ends up here.
+func (w *streamWriter) ReadFrom(src io.Reader) (int64, error) {
+	buf := w.buffers.Get().(*[]byte)
+	defer w.buffers.Put(buf)
+
+	// Hide the ReadFrom method of the underlying writer, so that io.CopyBuffer uses the buffer
+	return io.CopyBuffer(struct{ io.Writer }{w.ResponseWriter}, src, *buf)
+}
+
+// Unwrap returns the original http.ResponseWriter, so that http.ResponseController can reach it.
+func (w *streamWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// newBufferPool creates a pool of byte buffers of the given size.
+func newBufferPool(size int) *sync.Pool {
+	return &sync.Pool{
+		New: func() any {
+			buf := make([]byte, size)
+			return &buf
+		},
+	}
+}
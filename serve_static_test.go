@@ -0,0 +1,698 @@
+package httpserver_test
+
+import (
+	"compress/gzip"
+	"crypto/sha512"
+	"embed"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed testdata/embedded
+var embeddedFS embed.FS
+
+func testStaticFS() http.FileSystem {
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return http.FS(fstest.MapFS{
+		"index.html":          {Data: []byte(`<html><script nonce="{{nonce}}"></script></html>`), ModTime: modTime},
+		"app.js":              {Data: []byte("console.log('app')"), ModTime: modTime},
+		"private/config.json": {Data: []byte(`{"secret":true}`), ModTime: modTime},
+	})
+}
+
+func TestStaticHandlerNoStore(t *testing.T) {
+	handler := httpserver.StaticHandler("/static", testStaticFS(), time.Hour,
+		httpserver.WithNoStore(func(path string) bool {
+			return strings.HasPrefix(path, "/private/")
+		}),
+	)
+
+	t.Run("cached file", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/static/app.js", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "public, max-age=3600", rec.Header().Get("Cache-Control"))
+		require.NotEmpty(t, rec.Header().Get("ETag"))
+	})
+
+	t.Run("no-store file", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/static/private/config.json", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "no-store, no-cache", rec.Header().Get("Cache-Control"))
+		require.Empty(t, rec.Header().Get("ETag"))
+		require.Empty(t, rec.Header().Get("Last-Modified"))
+		require.Equal(t, `{"secret":true}`, rec.Body.String())
+	})
+
+	t.Run("no-store file ignores conditional headers", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/static/private/config.json", nil)
+		req.Header.Set("If-None-Match", "*")
+		req.Header.Set("If-Modified-Since", time.Now().UTC().Format(http.TimeFormat))
+
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestStaticHandlerLiveReload(t *testing.T) {
+	handler := httpserver.StaticHandler("/static", testStaticFS(), time.Hour, httpserver.WithLiveReload())
+
+	req := httptest.NewRequest(http.MethodGet, "/static/app.js", nil)
+	req.Header.Set("If-None-Match", "*")
+	req.Header.Set("If-Modified-Since", time.Now().UTC().Format(http.TimeFormat))
+
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "no-store, no-cache", rec.Header().Get("Cache-Control"))
+	require.Empty(t, rec.Header().Get("ETag"))
+	require.Empty(t, rec.Header().Get("Last-Modified"))
+	require.Empty(t, rec.Header().Get("Expires"))
+	require.Equal(t, "console.log('app')", rec.Body.String())
+}
+
+func TestStaticHandlerRestrictedExtensions(t *testing.T) {
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	root := http.FS(fstest.MapFS{
+		"index.html": {Data: []byte("<html></html>"), ModTime: modTime},
+		"app.js":     {Data: []byte("console.log('app')"), ModTime: modTime},
+		"app.js.map": {Data: []byte(`{"version":3}`), ModTime: modTime},
+	})
+	handler := httpserver.SPAHandler("/", root, "index.html", time.Hour,
+		httpserver.WithRestrictedExtensions([]string{"map"}, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}),
+	)
+
+	get := func(target, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	t.Run("allowed address", func(t *testing.T) {
+		rec := get("/app.js.map", "10.1.2.3:1234")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, `{"version":3}`, rec.Body.String())
+		require.Equal(t, "no-store, no-cache", rec.Header().Get("Cache-Control"))
+	})
+
+	t.Run("denied address", func(t *testing.T) {
+		rec := get("/app.js.map", "192.0.2.1:1234")
+		require.Equal(t, http.StatusNotFound, rec.Code)
+		require.NotContains(t, rec.Body.String(), "version")
+	})
+
+	t.Run("denied address, case-insensitive", func(t *testing.T) {
+		rec := get("/APP.JS.MAP", "192.0.2.1:1234")
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("public file", func(t *testing.T) {
+		rec := get("/app.js", "192.0.2.1:1234")
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "public, max-age=3600", rec.Header().Get("Cache-Control"))
+	})
+
+	t.Run("forwarded client", func(t *testing.T) {
+		handler := httpserver.StaticHandler("/", root, time.Hour,
+			httpserver.WithRestrictedExtensions([]string{"map"},
+				[]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+				netip.MustParsePrefix("192.0.2.0/24"),
+			),
+		)
+		get := func(remoteAddr, forwardedFor string) int {
+			req := httptest.NewRequest(http.MethodGet, "/app.js.map", nil)
+			req.RemoteAddr = remoteAddr
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			return rec.Code
+		}
+
+		// The client behind the trusted proxy is checked, not the proxy itself
+		require.Equal(t, http.StatusOK, get("192.0.2.1:1234", "10.1.2.3"))
+		require.Equal(t, http.StatusNotFound, get("192.0.2.1:1234", "198.51.100.7"))
+		// The headers of an untrusted peer are ignored
+		require.Equal(t, http.StatusNotFound, get("198.51.100.7:1234", "10.1.2.3"))
+	})
+}
+
+func TestStaticHandlerContentTypes(t *testing.T) {
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	root := http.FS(fstest.MapFS{
+		"README.md":        {Data: []byte("# Title\n"), ModTime: modTime},
+		"config.TOML":      {Data: []byte("key = 1\n"), ModTime: modTime},
+		"docs.rst":         {Data: []byte("Title\n=====\n"), ModTime: modTime},
+		"site.webmanifest": {Data: []byte(`{"name":"app"}`), ModTime: modTime},
+		"notes.markdown":   {Data: []byte("text"), ModTime: modTime},
+	})
+	handler := httpserver.StaticHandler("/", root, time.Hour,
+		httpserver.WithContentTypes(map[string]string{
+			"webmanifest": "application/manifest+json",
+		}),
+	)
+
+	for path, expected := range map[string]string{
+		"/README.md":        "text/plain; charset=utf-8",
+		"/config.TOML":      "text/plain; charset=utf-8",
+		"/docs.rst":         "text/plain; charset=utf-8",
+		"/site.webmanifest": "application/manifest+json",
+		"/notes.markdown":   "text/plain; charset=utf-8",
+	} {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, rec.Code, path)
+		require.Equal(t, expected, rec.Header().Get("Content-Type"), path)
+	}
+}
+
+func TestSPAHandlerCSPNonce(t *testing.T) {
+	handler := httpserver.SPAHandler("/", testStaticFS(), "index.html", time.Hour,
+		httpserver.WithCSPNonce("{{nonce}}", "script-src 'nonce-{{nonce}}'"),
+	)
+
+	nonceRe := regexp.MustCompile(`^script-src 'nonce-([A-Za-z0-9+/=]+)'$`)
+	nonces := make(map[string]bool)
+
+	for _, path := range []string{"/", "/index.html", "/some/client/route"} {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+		require.Equal(t, "no-store, no-cache", rec.Header().Get("Cache-Control"))
+		require.Empty(t, rec.Header().Get("ETag"))
+
+		m := nonceRe.FindStringSubmatch(rec.Header().Get("Content-Security-Policy"))
+		require.Len(t, m, 2, "unexpected CSP header: %s", rec.Header().Get("Content-Security-Policy"))
+		require.Equal(t, fmt.Sprintf(`<html><script nonce="%s"></script></html>`, m[1]), rec.Body.String())
+
+		require.False(t, nonces[m[1]], "nonce must be unique per request")
+		nonces[m[1]] = true
+	}
+
+	// Non-HTML files are served as is
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/app.js", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Empty(t, rec.Header().Get("Content-Security-Policy"))
+	require.Equal(t, "console.log('app')", rec.Body.String())
+}
+
+func TestStaticHandlerAllowedMethods(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		handler := httpserver.StaticHandler("/static", testStaticFS(), 0)
+
+		for _, method := range []string{http.MethodGet, http.MethodHead} {
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(method, "/static/app.js", nil))
+			require.Equal(t, http.StatusOK, rec.Code, method)
+		}
+
+		for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions} {
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(method, "/static/app.js", nil))
+			require.Equal(t, http.StatusMethodNotAllowed, rec.Code, method)
+			require.Equal(t, "GET, HEAD", rec.Header().Get("Allow"))
+		}
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		handler := httpserver.StaticHandler("/static", testStaticFS(), 0,
+			httpserver.WithAllowedMethods(http.MethodGet, http.MethodHead, "options"),
+		)
+
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodOptions, "/static/app.js", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		rec = httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, "/static/app.js", nil))
+		require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+		require.Equal(t, "GET, HEAD, OPTIONS", rec.Header().Get("Allow"))
+	})
+}
+
+func TestStaticHandlerImageAlternatives(t *testing.T) {
+	root := http.FS(fstest.MapFS{
+		"photo.jpg":  {Data: []byte("jpeg")},
+		"photo.webp": {Data: []byte("webp")},
+		"photo.avif": {Data: []byte("avif")},
+		"logo.png":   {Data: []byte("png")},
+		"logo.webp":  {Data: []byte("webp")},
+		"icon.gif":   {Data: []byte("gif")},
+	})
+	handler := httpserver.StaticHandler("/static", root, time.Hour, httpserver.WithImageAlternatives())
+
+	tests := []struct {
+		path     string
+		accept   string
+		expected string
+		ctype    string
+	}{
+		{path: "/static/photo.jpg", accept: "image/avif,image/webp,*/*", expected: "avif", ctype: "image/avif"},
+		{path: "/static/photo.jpg", accept: "image/webp,*/*", expected: "webp", ctype: "image/webp"},
+		{path: "/static/photo.jpg", accept: "image/avif;q=0,image/webp", expected: "webp", ctype: "image/webp"},
+		{path: "/static/photo.jpg", accept: "*/*", expected: "jpeg", ctype: "image/jpeg"},
+		{path: "/static/photo.jpg", accept: "", expected: "jpeg", ctype: "image/jpeg"},
+		{path: "/static/logo.png", accept: "image/avif,image/webp", expected: "webp", ctype: "image/webp"},
+		{path: "/static/icon.gif", accept: "image/avif,image/webp", expected: "gif", ctype: "image/gif"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+" "+tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Accept", tt.accept)
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			require.Equal(t, tt.expected, rec.Body.String())
+			require.Equal(t, tt.ctype, rec.Header().Get("Content-Type"))
+			require.Equal(t, "Accept", rec.Header().Get("Vary"))
+		})
+	}
+}
+
+func TestValidateStaticFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"static/index.html": {Data: []byte("index")},
+		"static/app.js":     {Data: []byte("app")},
+	}
+
+	require.NoError(t, httpserver.ValidateStaticFS(fsys))
+	require.NoError(t, httpserver.ValidateStaticFS(fsys, "static/index.html", "/static/app.js"))
+	require.ErrorIs(t, httpserver.ValidateStaticFS(fsys, "static/missing.html"), httpserver.ErrMissingStaticFile)
+	require.ErrorIs(t, httpserver.ValidateStaticFS(fsys, "static"), httpserver.ErrMissingStaticFile)
+	require.ErrorIs(t, httpserver.ValidateStaticFS(fstest.MapFS{}), httpserver.ErrEmptyStaticFS)
+	require.ErrorIs(t, httpserver.ValidateStaticFS(fstest.MapFS{"static": {Mode: fs.ModeDir}}), httpserver.ErrEmptyStaticFS)
+}
+
+func TestStaticHandlerDirectoryListing(t *testing.T) {
+	files := fstest.MapFS{}
+	for i := 0; i < 100; i++ {
+		files[fmt.Sprintf("docs/file-%03d.txt", i)] = &fstest.MapFile{Data: []byte("content")}
+	}
+	files["docs/sub/nested.txt"] = &fstest.MapFile{Data: []byte("nested")}
+	handler := httpserver.StaticHandler("/static", http.FS(files), 0, httpserver.WithDirectoryListing())
+
+	t.Run("plain", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/static/docs/", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+		require.Empty(t, rec.Header().Get("Content-Encoding"))
+		require.Contains(t, rec.Body.String(), `<a href="/static/docs/file-000.txt">file-000.txt</a>`)
+		require.Contains(t, rec.Body.String(), `<a href="/static/docs/sub/">sub/</a>`)
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/static/docs/", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+		require.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+
+		zr, err := gzip.NewReader(rec.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		require.Contains(t, string(body), `<a href="/static/docs/file-099.txt">file-099.txt</a>`)
+	})
+
+	t.Run("identity forbidden", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/static/docs/", nil)
+		req.Header.Set("Accept-Encoding", "identity;q=0, gzip")
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		httpserver.StaticHandler("/static", http.FS(files), 0)(rec, httptest.NewRequest(http.MethodGet, "/static/docs/", nil))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestStaticHandlerStreamBufferSize(t *testing.T) {
+	content := strings.Repeat("0123456789abcdef", 64*1024)
+	root := http.FS(fstest.MapFS{
+		"large.bin": {Data: []byte(content), ModTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	})
+	handler := httpserver.StaticHandler("/static", root, time.Hour, httpserver.WithStreamBufferSize(4096))
+
+	t.Run("full file", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/static/large.bin", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, fmt.Sprint(len(content)), rec.Header().Get("Content-Length"))
+		require.Equal(t, content, rec.Body.String())
+	})
+
+	t.Run("range", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/static/large.bin", nil)
+		req.Header.Set("Range", "bytes=16-31")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusPartialContent, rec.Code)
+		require.Equal(t, content[16:32], rec.Body.String())
+	})
+}
+
+func BenchmarkStaticHandlerStreamBufferSize(b *testing.B) {
+	dir := b.TempDir()
+	require.NoError(b, os.WriteFile(filepath.Join(dir, "large.bin"), make([]byte, 8<<20), 0o644))
+
+	for _, bc := range []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{name: "default", handler: httpserver.StaticHandler("/static", http.Dir(dir), time.Hour)},
+		{name: "256KB", handler: httpserver.StaticHandler("/static", http.Dir(dir), time.Hour, httpserver.WithStreamBufferSize(256<<10))},
+	} {
+		handler := bc.handler
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(8 << 20)
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					handler.ServeHTTP(discardResponseWriter{header: http.Header{}}, httptest.NewRequest(http.MethodGet, "/static/large.bin", nil))
+				}
+			})
+		})
+	}
+}
+
+// discardResponseWriter is a http.ResponseWriter which discards the response, for benchmarks.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w discardResponseWriter) Header() http.Header         { return w.header }
+func (w discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w discardResponseWriter) WriteHeader(int)             {}
+
+func TestStaticHandlerMountRoot(t *testing.T) {
+	handler := httpserver.StaticHandler("/static", testStaticFS(), 0)
+
+	t.Run("redirect", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/static?v=1", nil))
+		require.Equal(t, http.StatusMovedPermanently, rec.Code)
+		require.Equal(t, "static/?v=1", rec.Header().Get("Location"))
+	})
+
+	for _, target := range []string{"/static/", "/static/index.html"} {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		require.Equal(t, http.StatusOK, rec.Code, target)
+		require.Contains(t, rec.Body.String(), "<html>", target)
+	}
+
+	t.Run("directory without index", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/static/private/", nil))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestStaticHandlerDirectoryRedirect(t *testing.T) {
+	root := http.FS(fstest.MapFS{
+		"docs/index.html": {Data: []byte("<html>docs</html>")},
+		"index.html":      {Data: []byte("<html>app</html>")},
+	})
+
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		target   string
+		status   int
+		location string
+		body     string
+	}{
+		{
+			name:     "without trailing slash",
+			handler:  httpserver.StaticHandler("/static", root, 0),
+			target:   "/static/docs",
+			status:   http.StatusMovedPermanently,
+			location: "docs/",
+		},
+		{
+			name:     "query string is preserved",
+			handler:  httpserver.StaticHandler("/static", root, 0),
+			target:   "/static/docs?page=2&lang=en",
+			status:   http.StatusMovedPermanently,
+			location: "docs/?page=2&lang=en",
+		},
+		{
+			name:    "with trailing slash",
+			handler: httpserver.StaticHandler("/static", root, 0),
+			target:  "/static/docs/?page=2",
+			status:  http.StatusOK,
+			body:    "<html>docs</html>",
+		},
+		{
+			name:    "disabled without trailing slash",
+			handler: httpserver.StaticHandler("/static", root, 0, httpserver.WithDirectoryRedirect(false)),
+			target:  "/static/docs?page=2",
+			status:  http.StatusNotFound,
+		},
+		{
+			name:    "disabled with trailing slash",
+			handler: httpserver.StaticHandler("/static", root, 0, httpserver.WithDirectoryRedirect(false)),
+			target:  "/static/docs/",
+			status:  http.StatusOK,
+			body:    "<html>docs</html>",
+		},
+		{
+			name:    "disabled in SPA falls back to index",
+			handler: httpserver.SPAHandler("/app", root, "/index.html", 0, httpserver.WithDirectoryRedirect(false)),
+			target:  "/app/docs",
+			status:  http.StatusOK,
+			body:    "<html>app</html>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			require.Equal(t, tt.status, rec.Code)
+			require.Equal(t, tt.location, rec.Header().Get("Location"))
+			if tt.body != "" {
+				require.Equal(t, tt.body, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestStaticHandlerDotfiles(t *testing.T) {
+	root := http.FS(fstest.MapFS{
+		".env":                       {Data: []byte("SECRET=1")},
+		"sub/.git/config":            {Data: []byte("[core]")},
+		"sub/app.js":                 {Data: []byte("console.log('app')")},
+		".well-known/security.txt":   {Data: []byte("Contact: mailto:security@example.com")},
+		"docs/.htpasswd":             {Data: []byte("admin:hash")},
+		"docs/guide.txt":             {Data: []byte("guide")},
+		"docs/.hidden/readme.txt":    {Data: []byte("hidden")},
+		"docs/visible/.keep/ignored": {Data: []byte("")},
+	})
+
+	tests := []struct {
+		target   string
+		expected int
+	}{
+		{target: "/static/.env", expected: http.StatusNotFound},
+		{target: "/static/sub/.git/config", expected: http.StatusNotFound},
+		{target: "/static/sub/.git/", expected: http.StatusNotFound},
+		{target: "/static/docs/.htpasswd", expected: http.StatusNotFound},
+		{target: "/static/sub/app.js", expected: http.StatusOK},
+		{target: "/static/.well-known/security.txt", expected: http.StatusOK},
+	}
+
+	handler := httpserver.StaticHandler("/static", root, 0)
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		require.Equal(t, tt.expected, rec.Code, tt.target)
+	}
+
+	t.Run("spa does not fall back", func(t *testing.T) {
+		handler := httpserver.SPAHandler("/", root, "/sub/app.js", 0)
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/.env", nil))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("listing hides dotfiles", func(t *testing.T) {
+		handler := httpserver.StaticHandler("/static", root, 0, httpserver.WithDirectoryListing())
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/static/docs/", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Contains(t, rec.Body.String(), "guide.txt")
+		require.Contains(t, rec.Body.String(), "visible/")
+		require.NotContains(t, rec.Body.String(), ".htpasswd")
+		require.NotContains(t, rec.Body.String(), ".hidden")
+	})
+
+	t.Run("allowed", func(t *testing.T) {
+		handler := httpserver.StaticHandler("/static", root, 0, httpserver.WithDenyDotfiles(false))
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/static/.env", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "SECRET=1", rec.Body.String())
+	})
+}
+
+func TestIntegrityManifest(t *testing.T) {
+	files := fstest.MapFS{
+		"index.html":    {Data: []byte("<html></html>")},
+		"js/app.js":     {Data: []byte("console.log('app')")},
+		"fonts/a.woff2": {Data: []byte("font")},
+	}
+	sum := sha512.Sum384([]byte("console.log('app')"))
+	appIntegrity := "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+
+	m, err := httpserver.NewIntegrityManifest(files)
+	require.NoError(t, err)
+	require.Len(t, m.Manifest(), 3)
+	require.Equal(t, appIntegrity, m.Manifest()["/js/app.js"])
+
+	integrity, ok := m.Integrity("js/app.js")
+	require.True(t, ok)
+	require.Equal(t, appIntegrity, integrity)
+
+	handler := httpserver.StaticHandler("/static", http.FS(files), time.Hour, httpserver.WithIntegrity(m))
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/static/js/app.js", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, fmt.Sprintf(`</static/js/app.js>; rel=preload; as=script; integrity=%q`, appIntegrity), rec.Header().Get("Link"))
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/static/fonts/a.woff2", nil))
+	require.Contains(t, rec.Header().Get("Link"), "as=font")
+	require.Contains(t, rec.Header().Get("Link"), "; crossorigin")
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/static/index.html", nil))
+	require.Empty(t, rec.Header().Get("Link"))
+
+	t.Run("load", func(t *testing.T) {
+		loaded, err := httpserver.LoadIntegrityManifest(strings.NewReader(`{"js/app.js":"` + appIntegrity + `"}`))
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"/js/app.js": appIntegrity}, loaded.Manifest())
+
+		_, err = httpserver.LoadIntegrityManifest(strings.NewReader(`[]`))
+		require.ErrorIs(t, err, httpserver.ErrInvalidIntegrityManifest)
+	})
+}
+
+func TestStaticHandlerCDNFallback(t *testing.T) {
+	handler := httpserver.StaticHandler("/static", testStaticFS(), 0, httpserver.WithCDNFallback("https://cdn.example.com/assets/"))
+
+	t.Run("miss", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/static/img/logo%20dark.png?v=2", nil))
+		require.Equal(t, http.StatusFound, rec.Code)
+		require.Equal(t, "https://cdn.example.com/assets/img/logo%20dark.png?v=2", rec.Header().Get("Location"))
+	})
+
+	t.Run("hit", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/static/app.js", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("directory is not a miss", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/static/private/", nil))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		httpserver.StaticHandler("/static", testStaticFS(), 0)(rec, httptest.NewRequest(http.MethodGet, "/static/missing.js", nil))
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestEmbeddedStaticHandlerRange(t *testing.T) {
+	for _, cacheTTL := range []time.Duration{0, time.Hour} {
+		cacheTTL := cacheTTL
+		t.Run(cacheTTL.String(), func(t *testing.T) {
+			handler := httpserver.EmbeddedStaticHandler(embeddedFS, cacheTTL)
+			get := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				for k, v := range headers {
+					req.Header.Set(k, v)
+				}
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				return rec
+			}
+
+			// Embedded files have no modification time, so the ETag is derived from the content
+			rec := get("/testdata/embedded/hello.txt", nil)
+			require.Equal(t, http.StatusOK, rec.Code)
+			require.Empty(t, rec.Header().Get("Last-Modified"))
+			etag := rec.Header().Get("ETag")
+			require.Regexp(t, `^"[0-9a-f]{32}"$`, etag)
+			other := get("/testdata/embedded/other.txt", nil).Header().Get("ETag")
+			require.NotEqual(t, etag, other, "files of the same size must have different ETags")
+
+			rec = get("/testdata/embedded/hello.txt", map[string]string{"Range": "bytes=0-4"})
+			require.Equal(t, http.StatusPartialContent, rec.Code)
+			require.Equal(t, "bytes 0-4/23", rec.Header().Get("Content-Range"))
+			require.Equal(t, "Hello", rec.Body.String())
+
+			// A matching If-Range resumes the download, a stale one gets the full file
+			rec = get("/testdata/embedded/hello.txt", map[string]string{"Range": "bytes=7-", "If-Range": etag})
+			require.Equal(t, http.StatusPartialContent, rec.Code)
+			require.Equal(t, "bytes 7-22/23", rec.Header().Get("Content-Range"))
+			require.Equal(t, "embedded world!\n", rec.Body.String())
+
+			rec = get("/testdata/embedded/hello.txt", map[string]string{"Range": "bytes=7-", "If-Range": other})
+			require.Equal(t, http.StatusOK, rec.Code)
+			require.Equal(t, "Hello, embedded world!\n", rec.Body.String())
+
+			rec = get("/testdata/embedded/hello.txt", map[string]string{"If-None-Match": etag})
+			require.Equal(t, http.StatusNotModified, rec.Code)
+
+			// Without a modification time, If-Modified-Since cannot be evaluated
+			rec = get("/testdata/embedded/hello.txt", map[string]string{
+				"If-Modified-Since": time.Now().UTC().Format(http.TimeFormat),
+			})
+			require.Equal(t, http.StatusOK, rec.Code)
+		})
+	}
+}
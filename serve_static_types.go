@@ -0,0 +1,29 @@
+package httpserver
+
+import (
+	"maps"
+	"path"
+	"strings"
+)
+
+// defaultTextContentTypes maps text-like file extensions, which browsers would otherwise download
+// or which the system MIME database may lack, to UTF-8 plain text, so that they are rendered inline.
+var defaultTextContentTypes = map[string]string{
+	".md":       "text/plain; charset=utf-8",
+	".markdown": "text/plain; charset=utf-8",
+	".rst":      "text/plain; charset=utf-8",
+	".toml":     "text/plain; charset=utf-8",
+	".gmi":      "text/plain; charset=utf-8",
+}
+
+// newContentTypes returns a copy of the default content types of the static handlers.
+func newContentTypes() map[string]string {
+	return maps.Clone(defaultTextContentTypes)
+}
+
+// contentTypeFor returns the content type configured for the extension of the file path, if any.
+// Other files get the content type resolved by http.ServeContent from the extension or the content.
+func (cfg *staticConfig) contentTypeFor(fsPath string) (string, bool) {
+	ct, ok := cfg.contentTypes[strings.ToLower(path.Ext(fsPath))]
+	return ct, ok
+}
@@ -0,0 +1,64 @@
+package httpserver
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ValidateStaticFS checks that the file system contains at least one file
+// and that all the required files exist and are not directories.
+// Required paths are relative to the file system root, e.g. "static/index.html".
+// It is intended to catch misconfigured embed directives at startup rather than via a 404 in production.
+func ValidateStaticFS(fsys fs.FS, required ...string) error {
+	empty := true
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			empty = false
+			return fs.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Join(ErrInvalidStaticFS, err)
+	}
+	if empty {
+		return ErrEmptyStaticFS
+	}
+
+	for _, name := range required {
+		name = strings.TrimPrefix(name, "/")
+		info, err := fs.Stat(fsys, name)
+		if err != nil || info.IsDir() {
+			return fmt.Errorf("%w: %s", ErrMissingStaticFile, name)
+		}
+	}
+
+	return nil
+}
+
+// EmbeddedStaticHandlerValidated works like EmbeddedStaticHandler,
+// but validates the embedded file system with ValidateStaticFS first and returns an error if it is empty
+// or any of the required files is missing.
+//
+// Parameters:
+// - fs: The embed.FS representing the embedded file system.
+// - cacheTTL: The duration for which the client should cache the served files.
+// - required: The paths of the files which must exist, relative to the file system root.
+// - opts: Optional static handler options.
+//
+// Returns:
+// An http.HandlerFunc that serves static files with optional caching, or an error.
+func EmbeddedStaticHandlerValidated(fs embed.FS, cacheTTL time.Duration, required []string, opts ...staticOption) (http.HandlerFunc, error) {
+	if err := ValidateStaticFS(fs, required...); err != nil {
+		return nil, err
+	}
+	return EmbeddedStaticHandler(fs, cacheTTL, opts...), nil
+}
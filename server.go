@@ -2,12 +2,18 @@ package httpserver
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
+	"fmt"
+	"log"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/signal"
-	"syscall"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/sync/errgroup"
@@ -18,9 +24,57 @@ import (
 // It also provides a Run function to start an HTTP server with graceful shutdown.
 // The server is stopped gracefully when the context is cancelled or a shutdown signal is received.
 type Server struct {
-	httpServer      *http.Server
-	shutdownTimeout time.Duration
-	log             Logger
+	httpServer              *http.Server
+	shutdownTimeout         time.Duration
+	log                     Logger
+	acceptErrorHandler      func(error) bool
+	strictTLS               bool
+	inflight                *inflightTracker
+	listeners               []additionalListener
+	shuttingDown            atomic.Bool
+	logFields               []interface{}
+	conns                   *connTracker
+	healthChecks            []*healthCheck
+	unhealthyCheck          string
+	unhealthyAfter          time.Duration
+	errorLogSet             bool
+	handlerBuilder          func(*Server) http.Handler
+	shutdownResponse        *fixedResponse
+	timeoutResponse         *fixedResponse
+	instrumentation         Instrumentation
+	gracefulFraction        float64
+	requestTimeout          time.Duration
+	forceQuitOnSecondSignal bool
+	middlewares             []func(http.Handler) http.Handler
+	maxDecompressedSize     int64
+	listenConfig            *net.ListenConfig
+	startupValidators       []func() error
+	shutdownSafeResponses   bool
+	absoluteRequestTimeout  time.Duration
+	readinessFile           string
+	maxIdleConns            int
+	acceptGate              acceptGate
+	connStateLogging        bool
+	adaptiveShutdown        *adaptiveShutdown
+	responseHeaders         http.Header
+	priorities              *priorityTracker
+	nonCriticalTimeout      time.Duration
+	clientIPLogging         bool
+	trustedProxies          []netip.Prefix
+	errorRenderer           ErrorRenderer
+	tcpNoDelay              *bool
+	streamTimeout           time.Duration
+	shutdownNotify          []chan<- struct{}
+	readBufferSize          int
+	writeBufferSize         int
+	shutdownDelay           time.Duration
+	maxHeaderValueLen       int
+	signalActions           map[os.Signal]ShutdownAction
+	baseParent              context.Context
+	requestStats            bool
+	handler                 atomic.Pointer[http.Handler]
+	listener                net.Listener
+	stats                   serverStats
 }
 
 // Logger is an interface that defines the logging methods used by the server.
@@ -39,13 +93,13 @@ type Logger interface {
 // The opt parameter is a variadic list of server options.
 // The server options are applied in order, so the last option overrides the previous ones.
 // The server options are applied before the server is started.
+//
+// The handler may be nil if WithHandlerBuilder is used, in which case the handler is built
+// at the end of New, after all the options are applied and before the server middlewares wrap it.
 func New(addr string, handler http.Handler, opt ...serverOption) (*Server, error) {
 	if addr == "" {
 		return nil, ErrEmptyAddress
 	}
-	if handler == nil {
-		return nil, ErrNilHandler
-	}
 
 	s := &Server{
 		httpServer: &http.Server{
@@ -56,8 +110,10 @@ func New(addr string, handler http.Handler, opt ...serverOption) (*Server, error
 			IdleTimeout:    15 * time.Second,
 			MaxHeaderBytes: 1 << 20, // 1 MB
 		},
-		shutdownTimeout: 5 * time.Second,
-		log:             slog.Default().With(slog.String("component", "httpserver")),
+		shutdownTimeout:  5 * time.Second,
+		gracefulFraction: 1,
+		log:              slog.Default().With(slog.String("component", "httpserver")),
+		conns:            newConnTracker(),
 	}
 
 	// Apply options
@@ -65,14 +121,141 @@ func New(addr string, handler http.Handler, opt ...serverOption) (*Server, error
 		o(s)
 	}
 
+	if s.handlerBuilder != nil {
+		s.httpServer.Handler = s.handlerBuilder(s)
+	}
+	if s.httpServer.Handler == nil {
+		return nil, ErrNilHandler
+	}
+	if s.listenConfig != nil && s.listener != nil {
+		return nil, ErrListenConfigConflict
+	}
+	if s.gracefulFraction <= 0 || s.gracefulFraction > 1 {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidShutdownPhases, s.gracefulFraction)
+	}
+
+	if len(s.logFields) > 0 {
+		s.log = &fieldsLogger{Logger: s.log, fields: s.logFields}
+	}
+	if s.instrumentation != nil {
+		s.log = s.instrumentation.Logger(s.log)
+	}
+
+	// Route net/http internal errors, e.g. TLS handshake failures, through the server logger
+	if !s.errorLogSet && s.httpServer.ErrorLog == nil {
+		s.httpServer.ErrorLog = log.New(&logWriter{log: s.log}, "", 0)
+	}
+
+	s.httpServer.Handler = s.wrapHandler(s.httpServer.Handler)
+	if s.instrumentation != nil {
+		s.httpServer.Handler = s.instrumentation.Handler(s.httpServer.Handler)
+	}
+	s.setBaseContext()
+	s.setConnState()
+	if s.absoluteRequestTimeout > 0 || s.priorities != nil {
+		s.setConnContext()
+	}
+
 	return s, nil
 }
 
+// wrapHandler wraps the handler with the middlewares enabled by the server options.
+// The middlewares set with WithMiddleware wrap the handler first, so the server middlewares are the outermost.
+func (s *Server) wrapHandler(h http.Handler) http.Handler {
+	// Serve the current handler, so that it can be replaced with SetHandler
+	s.SetHandler(h)
+	h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		(*s.handler.Load()).ServeHTTP(w, r)
+	})
+	for i := len(s.middlewares) - 1; i >= 0; i-- {
+		h = s.middlewares[i](h)
+	}
+	for _, al := range s.listeners {
+		if al.handler != nil {
+			h = listenerHandlerMiddleware(h)
+			break
+		}
+	}
+	if s.maxDecompressedSize > 0 {
+		h = s.requestDecompressionMiddleware(h)
+	}
+	if s.requestTimeout > 0 {
+		h = s.requestTimeoutMiddleware(h)
+	}
+	if s.streamTimeout > 0 {
+		h = s.streamTimeoutMiddleware(h)
+	}
+	if s.absoluteRequestTimeout > 0 {
+		h = s.absoluteTimeoutMiddleware(h)
+	}
+	if s.priorities != nil {
+		h = s.priorityMiddleware(h)
+	}
+	if s.shutdownSafeResponses {
+		h = s.shutdownSafeMiddleware(h)
+	}
+	if s.inflight != nil {
+		h = s.inflight.middleware(h)
+	}
+	if s.shutdownResponse != nil {
+		h = s.shutdownResponderMiddleware(h)
+	}
+	if s.maxHeaderValueLen > 0 {
+		h = s.maxHeaderValueLengthMiddleware(h)
+	}
+	h = s.statsMiddleware(h)
+	if s.shutdownDelay > 0 {
+		h = s.drainMiddleware(h)
+	}
+	if len(s.responseHeaders) > 0 {
+		h = s.responseHeadersMiddleware(h)
+	}
+	return h
+}
+
 // Start starts the server and listens for incoming requests.
 // It uses the provided context to handle graceful shutdown.
 // The context is also used to handle shutdown signals from the OS.
 // It returns an error if the server fails to start or encounters an error during shutdown.
+//
+// Start serves plain HTTP even if a TLS configuration with certificates is set.
+// Such a misconfiguration is logged, or rejected with ErrTLSConfigIgnored if WithStrictTLS is used.
+// Use StartTLS to serve HTTPS.
 func (s *Server) Start(ctx context.Context) error {
+	if hasTLSCertificates(s.httpServer.TLSConfig) {
+		if s.strictTLS {
+			return errors.Join(ErrServerStart, ErrTLSConfigIgnored)
+		}
+		s.log.ErrorContext(ctx, "TLS certificates are configured, but the server is started without TLS, use StartTLS to serve HTTPS")
+	}
+
+	return s.start(ctx, s.httpServer.Serve)
+}
+
+// StartTLS starts the server and listens for incoming HTTPS requests.
+// It behaves like Start, but serves TLS connections.
+// The certFile and keyFile parameters are paths to the certificate and the matching private key files.
+// They may be empty if the certificates are provided via WithTLSConfig.
+// It returns ErrNoTLSCertificate, joined with ErrServerStart, without starting the server if no certificate is available at all.
+func (s *Server) StartTLS(ctx context.Context, certFile, keyFile string) error {
+	if certFile == "" && keyFile == "" && !hasTLSCertificates(s.httpServer.TLSConfig) {
+		return errors.Join(ErrServerStart, ErrNoTLSCertificate)
+	}
+
+	return s.start(ctx, func(ln net.Listener) error {
+		return s.httpServer.ServeTLS(ln, certFile, keyFile)
+	})
+}
+
+// start runs the server using the given serve function until the context is cancelled
+// or a shutdown signal is received.
+func (s *Server) start(ctx context.Context, serve func(net.Listener) error) error {
+	for _, validate := range s.startupValidators {
+		if err := validate(); err != nil {
+			return errors.Join(ErrServerStart, err)
+		}
+	}
+
 	s.log.InfoContext(ctx, "starting HTTP server",
 		"addr", s.httpServer.Addr,
 		"read_timeout", s.httpServer.ReadTimeout,
@@ -80,30 +263,108 @@ func (s *Server) Start(ctx context.Context) error {
 		"idle_timeout", s.httpServer.IdleTimeout,
 	)
 
-	// Create a new context for shutdown
-	shutdownCtx, shutdownCancel := context.WithCancel(context.Background())
+	// Shut down when either the given or the base parent context is done
+	if s.baseParent != nil {
+		var stop func()
+		ctx, stop = MergeContexts(ctx, s.baseParent)
+		defer stop()
+	}
+
+	// Create a new context for shutdown, which keeps the values of ctx, e.g. the trace span, but not its cancellation
+	shutdownCtx, shutdownCancel := context.WithCancel(context.WithoutCancel(ctx))
 	defer shutdownCancel()
 
+	// Allow the server to initiate its own shutdown, e.g. on a failing health check
+	ctx, initiateShutdown := context.WithCancelCause(ctx)
+	defer initiateShutdown(nil)
+	lifecycleCtx := ctx
+
 	// creates ctx which will be canceled on first failed goroutine
 	g, ctx := errgroup.WithContext(ctx)
 
+	// Track the listeners being bound, so that the server is reported ready once all of them are listening
+	var bound sync.WaitGroup
+	bound.Add(1 + len(s.listeners))
+	defer s.removeReadinessFile(shutdownCtx)
+
 	// Start the server in a new goroutine within the errgroup
 	g.Go(func() error {
-		if err := s.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		ln, err := s.mainListener(ctx)
+		bound.Done()
+		if err != nil {
+			return errors.Join(ErrServerStart, err)
+		}
+		s.stats.startedAt.Store(time.Now().UnixNano())
+		s.log.InfoContext(ctx, "listening", "addr", ln.Addr().String(), "handler", "server")
+		if err := serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			return errors.Join(ErrServerStart, err)
 		}
 		return nil
 	})
 
+	// Start additional listeners sharing the same handler and lifecycle
+	for _, al := range s.listeners {
+		al := al
+		g.Go(func() error {
+			ln, err := s.listen(ctx, al.addr)
+			bound.Done()
+			if err != nil {
+				return errors.Join(ErrServerStart, err)
+			}
+			if al.tlsConfig != nil {
+				ln = tls.NewListener(ln, al.tlsConfig)
+			}
+			handlerName := "server"
+			if al.handler != nil {
+				ln = &handlerListener{Listener: ln, handler: al.handler}
+				handlerName = fmt.Sprintf("%T", al.handler)
+			}
+			s.log.InfoContext(ctx, "listening", "addr", ln.Addr().String(), "tls", al.tlsConfig != nil, "handler", handlerName)
+			if err := s.httpServer.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return errors.Join(ErrServerStart, err)
+			}
+			return nil
+		})
+	}
+
+	// Report readiness once listening, unless a listener failed
+	if s.readinessFile != "" {
+		g.Go(func() error {
+			bound.Wait()
+			if ctx.Err() == nil {
+				s.createReadinessFile(ctx)
+			}
+			return nil
+		})
+	}
+
+	// Watch the health check, if configured
+	if s.unhealthyCheck != "" {
+		g.Go(func() error {
+			s.monitorHealth(ctx, s.unhealthyCheck, s.unhealthyAfter, func() {
+				initiateShutdown(ErrServerUnhealthy)
+			})
+			return nil
+		})
+	}
+
 	// Handle shutdown signals
+	signals := signalChan(s.shutdownSignals()...)
 	g.Go(func() error {
 		select {
 		case <-ctx.Done():
 			s.log.InfoContext(ctx, "context cancelled, initiating shutdown")
-			return s.Stop(shutdownCtx, s.shutdownTimeout)
-		case sig := <-signalChan():
-			s.log.InfoContext(ctx, "received shutdown signal", "signal", sig.String())
-			return s.Stop(shutdownCtx, s.shutdownTimeout)
+			return s.Stop(shutdownCtx, s.gracefulShutdownTimeout(shutdownCtx))
+		case sig := <-signals:
+			action := s.signalAction(sig)
+			s.log.InfoContext(ctx, "received shutdown signal", "signal", sig.String(), "action", action.String())
+			if action == ImmediateClose {
+				return s.Close(shutdownCtx)
+			}
+			if s.forceQuitOnSecondSignal {
+				return s.stopOrForceQuit(shutdownCtx, signals)
+			}
+			return s.Stop(shutdownCtx, s.gracefulShutdownTimeout(shutdownCtx))
 		}
 	})
 
@@ -113,20 +374,89 @@ func (s *Server) Start(ctx context.Context) error {
 		return err
 	}
 
+	if cause := context.Cause(lifecycleCtx); errors.Is(cause, ErrServerUnhealthy) {
+		s.log.ErrorContext(ctx, "server stopped due to a failing health check")
+		return cause
+	}
+
 	s.log.InfoContext(ctx, "server stopped gracefully")
 	return nil
 }
 
+// listen creates the network listener for the given address
+// and wraps it according to the server options.
+// The listen config set with WithListenConfig is used if any.
+func (s *Server) listen(ctx context.Context, addr string) (net.Listener, error) {
+	lc := s.listenConfig
+	if lc == nil {
+		lc = &net.ListenConfig{}
+	}
+	ln, err := lc.Listen(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return s.wrapListener(ctx, ln), nil
+}
+
+// mainListener returns the listener for the server address,
+// which is either provided with WithListener or created by listen.
+func (s *Server) mainListener(ctx context.Context) (net.Listener, error) {
+	if s.listener != nil {
+		return s.wrapListener(ctx, s.listener), nil
+	}
+	return s.listen(ctx, s.httpServer.Addr)
+}
+
+// wrapListener wraps the listener according to the server options.
+func (s *Server) wrapListener(ctx context.Context, ln net.Listener) net.Listener {
+	if s.acceptErrorHandler != nil {
+		ln = &acceptErrorListener{
+			Listener: ln,
+			ctx:      ctx,
+			handler:  s.acceptErrorHandler,
+			log:      s.log,
+		}
+	}
+	if s.tcpNoDelay != nil || s.readBufferSize > 0 || s.writeBufferSize > 0 {
+		ln = &tcpOptionsListener{
+			Listener:    ln,
+			ctx:         ctx,
+			log:         s.log,
+			noDelay:     s.tcpNoDelay,
+			readBuffer:  s.readBufferSize,
+			writeBuffer: s.writeBufferSize,
+		}
+	}
+	ln = &gatedListener{Listener: ln, gate: &s.acceptGate, closed: make(chan struct{})}
+
+	return ln
+}
+
 // Stop stops the server gracefully with the given timeout.
 // It uses the provided timeout to gracefully shutdown the underlying HTTP server.
 // If the timeout is reached before the server is fully stopped, an error is returned.
+// With WithShutdownDelay, the server keeps serving for the delay before the timeout starts.
+//
+// With WithShutdownPhases, only a fraction of the timeout is spent on the graceful shutdown.
+// If it does not finish in time, the in-flight requests are logged and the rest of the timeout
+// is given to them to complete before the server is force closed.
 func (s *Server) Stop(ctx context.Context, timeout time.Duration) error {
 	s.log.InfoContext(ctx, "stopping HTTP server", "timeout", timeout)
+	s.beginShutdown(ctx)
+	s.waitShutdownDelay(ctx)
+	drained := len(s.conns.snapshot())
 
-	// Create a new context for shutdown with timeout
-	shutdownCtx, cancel := context.WithTimeout(ctx, timeout)
+	// Create a new context for shutdown with the graceful phase timeout
+	gracefulTimeout := time.Duration(float64(timeout) * s.gracefulFraction)
+	shutdownCtx, cancel := context.WithTimeout(ctx, gracefulTimeout)
 	defer cancel()
 
+	// Close the non-critical requests early, leaving the full timeout to the critical ones
+	if s.priorities != nil && s.nonCriticalTimeout < timeout {
+		t := time.AfterFunc(s.nonCriticalTimeout, func() { s.closeNonCriticalRequests(ctx) })
+		defer t.Stop()
+	}
+
 	// Create an error group for coordinated shutdown
 	g := new(errgroup.Group)
 
@@ -143,19 +473,101 @@ func (s *Server) Stop(ctx context.Context, timeout time.Duration) error {
 	// Wait for shutdown to complete or timeout
 	if err := g.Wait(); err != nil {
 		s.log.ErrorContext(ctx, "error during server shutdown", "error", err)
+		if errors.Is(err, context.DeadlineExceeded) {
+			s.logInflightRequests(ctx)
+			if remaining := timeout - gracefulTimeout; remaining > 0 && s.finishWithin(ctx, remaining) {
+				s.log.InfoContext(ctx, "HTTP server shutdown complete after the graceful phase", "drained_connections", drained)
+				return nil
+			}
+		}
 		// Force close if graceful shutdown fails
 		_ = s.Close(ctx)
 		return err
 	}
 
-	s.log.InfoContext(ctx, "HTTP server shutdown complete")
+	s.log.InfoContext(ctx, "HTTP server shutdown complete", "drained_connections", drained)
 	return nil
 }
 
+// stopOrForceQuit stops the server gracefully, unless another signal is received meanwhile,
+// in which case the server is closed immediately.
+func (s *Server) stopOrForceQuit(ctx context.Context, signals <-chan os.Signal) error {
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- s.Stop(ctx, s.gracefulShutdownTimeout(ctx))
+	}()
+
+	select {
+	case err := <-stopped:
+		return err
+	case sig := <-signals:
+		s.log.InfoContext(ctx, "received second shutdown signal, forcing close", "signal", sig.String())
+		err := s.Close(ctx)
+		<-stopped
+		return err
+	}
+}
+
+// finishWithin waits up to d for the active connections to finish after the graceful shutdown phase has expired.
+// It reports whether they all finished in time.
+func (s *Server) finishWithin(ctx context.Context, d time.Duration) bool {
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+
+	// Shutdown has already closed the listeners, so calling it again only waits for the connections
+	err := s.httpServer.Shutdown(ctx)
+	return !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled)
+}
+
+// SetHandler replaces the server handler at runtime without restarting the listeners,
+// e.g. to apply a new routing configuration. Requests which are being handled keep using the old handler,
+// and new requests are served by the new one. The handler is wrapped by the same middlewares
+// as the one passed to New, including the ones set with WithMiddleware. A nil handler is ignored.
+// It is safe to call SetHandler concurrently with serving requests.
+func (s *Server) SetHandler(h http.Handler) {
+	if h == nil {
+		return
+	}
+	s.handler.Store(&h)
+}
+
+// beginShutdown marks the server as shutting down and, the first time, notifies the channels
+// set with WithShutdownNotify. The readiness file is removed, so that no new traffic is routed to the server.
+func (s *Server) beginShutdown(ctx context.Context) {
+	if !s.shuttingDown.Swap(true) {
+		for _, ch := range s.shutdownNotify {
+			close(ch)
+		}
+	}
+	s.removeReadinessFile(ctx)
+}
+
+// waitShutdownDelay keeps the server serving for the delay set with WithShutdownDelay after the shutdown began,
+// so that load balancers notice the failing readiness and stop routing new traffic before the listeners close.
+func (s *Server) waitShutdownDelay(ctx context.Context) {
+	if s.shutdownDelay <= 0 {
+		return
+	}
+	s.log.InfoContext(ctx, "waiting for load balancers to stop routing traffic", "delay", s.shutdownDelay)
+
+	t := time.NewTimer(s.shutdownDelay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+// ShuttingDown reports whether the server has started shutting down.
+func (s *Server) ShuttingDown() bool {
+	return s.shuttingDown.Load()
+}
+
 // Close stops the server immediately without waiting for active connections to finish.
 // It returns an error if the server fails to stop.
 func (s *Server) Close(ctx context.Context) error {
 	s.log.InfoContext(ctx, "force closing HTTP server")
+	s.beginShutdown(ctx)
 
 	if err := s.httpServer.Close(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		s.log.ErrorContext(ctx, "error during force close", "error", err)
@@ -164,10 +576,19 @@ func (s *Server) Close(ctx context.Context) error {
 	return nil
 }
 
-// signalChan sets up a channel to listen for OS signals for shutdown
-func signalChan() <-chan os.Signal {
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+// hasTLSCertificates reports whether the TLS configuration provides any certificate.
+func hasTLSCertificates(cfg *tls.Config) bool {
+	return cfg != nil && (len(cfg.Certificates) > 0 || cfg.GetCertificate != nil || cfg.GetConfigForClient != nil)
+}
+
+// signalChan sets up a channel to listen for OS signals for shutdown.
+// It is a variable so that tests can simulate signals.
+var signalChan = defaultSignalChan
+
+// defaultSignalChan subscribes to the given signals.
+func defaultSignalChan(signals ...os.Signal) <-chan os.Signal {
+	stop := make(chan os.Signal, 2)
+	signal.Notify(stop, signals...)
 	return stop
 }
 
@@ -183,3 +604,41 @@ func Run(ctx context.Context, addr string, handler http.Handler) error {
 	}
 	return server.Start(ctx)
 }
+
+// RunUntilSignal creates an HTTP server on the specified address with the given handler and options,
+// and blocks until it is stopped, typically by SIGINT or SIGTERM, returning after the graceful shutdown.
+// It is the simplest way to run a server from main when the caller does not manage a context:
+// the server uses its own background context and installs the signal handlers itself,
+// so the signals no longer terminate the process by default while it runs.
+// It returns nil on a clean shutdown, so main can exit with a non-zero code on any error, e.g.:
+//
+//	if err := httpserver.RunUntilSignal(":8080", mux); err != nil {
+//		log.Fatal(err)
+//	}
+func RunUntilSignal(addr string, handler http.Handler, opts ...serverOption) error {
+	server, err := New(addr, handler, opts...)
+	if err != nil {
+		return err
+	}
+	return server.Start(context.Background())
+}
+
+// RunListener starts an HTTP server on the provided listener and runs the provided handler.
+// It has the same graceful shutdown semantics as Run; the listener is closed when the server shuts down.
+// This is useful for tests (e.g. a listener on "localhost:0") and for custom listeners.
+func RunListener(ctx context.Context, ln net.Listener, handler http.Handler) error {
+	server, err := New(ln.Addr().String(), handler, WithListener(ln))
+	if err != nil {
+		return err
+	}
+	return server.Start(ctx)
+}
+
+// RunDefault starts an HTTP server on the specified address serving http.DefaultServeMux,
+// like http.ListenAndServe(addr, nil), with the same graceful shutdown semantics as Run.
+// It is meant for quick scripts and prototypes registering their handlers globally with http.HandleFunc.
+// Note that some packages register handlers on the default mux when imported, e.g. net/http/pprof,
+// so they are exposed too; prefer Run with a dedicated mux in production.
+func RunDefault(ctx context.Context, addr string) error {
+	return Run(ctx, addr, http.DefaultServeMux)
+}
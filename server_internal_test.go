@@ -0,0 +1,214 @@
+package httpserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestForceQuitOnSecondSignal(t *testing.T) {
+	signals := make(chan os.Signal, 2)
+	signalChan = func(...os.Signal) <-chan os.Signal { return signals }
+	t.Cleanup(func() { signalChan = defaultSignalChan })
+
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	})
+	s, err := New(ln.Addr().String(), handler,
+		WithListener(ln),
+		WithGracefulShutdown(time.Minute),
+		WithForceQuitOnSecondSignal(),
+	)
+	require.NoError(t, err)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- s.Start(context.Background())
+	}()
+
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+
+	// The first signal starts the graceful shutdown, which waits for the hanging request
+	signals <- syscall.SIGINT
+	require.Eventually(t, s.ShuttingDown, time.Second, 10*time.Millisecond)
+	select {
+	case <-serverErr:
+		t.Fatal("server stopped before the second signal")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// The second signal closes the server immediately
+	signals <- syscall.SIGINT
+	select {
+	case err := <-serverErr:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("server was not force closed")
+	}
+}
+
+func TestSignalHandler(t *testing.T) {
+	signals := make(chan os.Signal, 1)
+	signalChan = func(...os.Signal) <-chan os.Signal { return signals }
+	t.Cleanup(func() { signalChan = defaultSignalChan })
+
+	// start serves a request which hangs until released, and sends the signal once it is being handled
+	start := func(t *testing.T, sig os.Signal) (release func(), serverErr <-chan error) {
+		ln, err := net.Listen("tcp", "localhost:0")
+		require.NoError(t, err)
+
+		started, released := make(chan struct{}), make(chan struct{})
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			select {
+			case <-released:
+			case <-r.Context().Done():
+			}
+		})
+		s, err := New(ln.Addr().String(), handler,
+			WithListener(ln),
+			WithGracefulShutdown(time.Minute),
+			WithSignalHandler(os.Interrupt, ImmediateClose),
+			WithSignalHandler(syscall.SIGTERM, GracefulDrain),
+		)
+		require.NoError(t, err)
+
+		errCh := make(chan error, 1)
+		go func() { errCh <- s.Start(context.Background()) }()
+		go func() {
+			resp, err := http.Get("http://" + ln.Addr().String())
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+		<-started
+
+		signals <- sig
+		require.Eventually(t, s.ShuttingDown, time.Second, 10*time.Millisecond)
+		return func() { close(released) }, errCh
+	}
+
+	t.Run("SIGTERM drains gracefully", func(t *testing.T) {
+		release, serverErr := start(t, syscall.SIGTERM)
+		select {
+		case <-serverErr:
+			t.Fatal("server stopped before the request completed")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		release()
+		select {
+		case err := <-serverErr:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("server did not stop after the request completed")
+		}
+	})
+
+	t.Run("SIGINT closes immediately", func(t *testing.T) {
+		release, serverErr := start(t, os.Interrupt)
+		defer release()
+		select {
+		case err := <-serverErr:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("server was not closed immediately")
+		}
+	})
+
+	t.Run("extra signals are subscribed", func(t *testing.T) {
+		s, err := New("localhost:0", http.NotFoundHandler(), WithSignalHandler(syscall.SIGHUP, GracefulDrain))
+		require.NoError(t, err)
+		require.ElementsMatch(t, []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP}, s.shutdownSignals())
+		require.Equal(t, GracefulDrain, s.signalAction(syscall.SIGQUIT))
+		require.Contains(t, s.Config().Features, "WithSignalHandler")
+	})
+}
+
+func TestRunUntilSignal(t *testing.T) {
+	signals := make(chan os.Signal, 1)
+	signalChan = func(...os.Signal) <-chan os.Signal { return signals }
+	t.Cleanup(func() { signalChan = defaultSignalChan })
+
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- RunUntilSignal(ln.Addr().String(), http.NotFoundHandler(), WithListener(ln))
+	}()
+
+	resp, err := http.Get("http://" + ln.Addr().String())
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	signals <- syscall.SIGTERM
+	select {
+	case err := <-serverErr:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("server did not stop on the signal")
+	}
+
+	t.Run("invalid configuration", func(t *testing.T) {
+		require.ErrorIs(t, RunUntilSignal("", http.NotFoundHandler()), ErrEmptyAddress)
+	})
+}
+
+func TestAdaptiveShutdownTimeout(t *testing.T) {
+	log := &MemoryLogger{}
+	s, err := New("localhost:0", http.NotFoundHandler(),
+		WithLogger(log),
+		WithAdaptiveShutdownTimeout(time.Second, 500*time.Millisecond, 3*time.Second),
+	)
+	require.NoError(t, err)
+
+	conns := func(n int, state http.ConnState) {
+		for i := 0; i < n; i++ {
+			c, _ := net.Pipe()
+			t.Cleanup(func() { _ = c.Close() })
+			s.conns.track(c, state)
+		}
+	}
+
+	require.Equal(t, time.Second, s.gracefulShutdownTimeout(context.Background()))
+
+	// Idle connections do not count
+	conns(5, http.StateIdle)
+	require.Equal(t, time.Second, s.gracefulShutdownTimeout(context.Background()))
+
+	conns(2, http.StateActive)
+	require.Equal(t, 2*time.Second, s.gracefulShutdownTimeout(context.Background()))
+
+	conns(10, http.StateActive)
+	require.Equal(t, 3*time.Second, s.gracefulShutdownTimeout(context.Background()))
+
+	entries := log.Find("computed adaptive shutdown timeout")
+	require.Len(t, entries, 4)
+	active, _ := entries[3].Field("active_connections")
+	require.Equal(t, 12, active)
+
+	t.Run("fixed timeout by default", func(t *testing.T) {
+		s, err := New("localhost:0", http.NotFoundHandler(), WithGracefulShutdown(7*time.Second))
+		require.NoError(t, err)
+		require.Equal(t, 7*time.Second, s.gracefulShutdownTimeout(context.Background()))
+	})
+}
@@ -1,9 +1,13 @@
 package httpserver
 
 import (
+	"context"
 	"crypto/tls"
 	"log"
+	"net"
 	"net/http"
+	"net/netip"
+	"os"
 	"time"
 )
 
@@ -17,6 +21,115 @@ func WithPreconfiguredServer(s *http.Server) serverOption {
 	}
 }
 
+// WithBaseParentContext sets the parent of every request context, so that app-wide values, e.g. a logger
+// or the configuration, are available to the handlers via r.Context() without a separate middleware.
+// This is useful with RunUntilSignal, which starts the server with a background context.
+// It takes precedence over the BaseContext of a server set with WithPreconfiguredServer.
+//
+// Only the values of the parent flow to the requests, not its cancellation. Instead, the parent is merged
+// with the context given to Start: the server shuts down gracefully when either of them is done,
+// and the in-flight requests keep their contexts alive until they complete or the shutdown timeout expires.
+// The lifecycle logs use the values of the Start context. A nil context is ignored.
+func WithBaseParentContext(ctx context.Context) serverOption {
+	return func(srv *Server) {
+		if ctx != nil {
+			srv.baseParent = ctx
+		}
+	}
+}
+
+// WithHandlerBuilder sets a function which builds the server handler once the server exists,
+// for handlers which need a reference to the server, e.g. to serve a shutdown status page.
+// The builder is called at the end of New, after all the options are applied,
+// so the server is fully configured but not started yet. The built handler replaces the one passed to New,
+// which may be nil in this case.
+func WithHandlerBuilder(fn func(*Server) http.Handler) serverOption {
+	return func(srv *Server) {
+		srv.handlerBuilder = fn
+	}
+}
+
+// WithInstrumentation sets the instrumentation observing the server requests and lifecycle,
+// e.g. the OpenTelemetry tracing provided by the otelserver subpackage.
+func WithInstrumentation(i Instrumentation) serverOption {
+	return func(srv *Server) {
+		srv.instrumentation = i
+	}
+}
+
+// WithTCPNoDelay explicitly sets TCP_NODELAY on the accepted TCP connections, including the additional listeners.
+// When enabled, Nagle's algorithm is disabled and small writes are sent right away, which lowers the latency
+// of small responses, e.g. of an API, at the cost of more packets on the wire. When disabled, small writes
+// are coalesced into fewer, fuller packets, which favors throughput but may delay responses by up to
+// a round trip. Go already enables TCP_NODELAY by default; without the option it is left untouched.
+func WithTCPNoDelay(enabled bool) serverOption {
+	return func(srv *Server) {
+		srv.tcpNoDelay = &enabled
+	}
+}
+
+// WithSocketBufferSizes sets the sizes of the socket receive and send buffers (SO_RCVBUF and SO_SNDBUF)
+// of the accepted TCP connections, including the additional listeners. A non-positive size keeps the OS default.
+// The OS may adjust the sizes, e.g. Linux doubles them and clamps them to net.core.rmem_max and wmem_max;
+// the sizes in effect are logged once per listener where they can be read.
+//
+// It is meant for moving large files or streams over links with a high bandwidth-delay product:
+// the throughput of a connection is roughly bounded by the buffer size divided by the round-trip time,
+// e.g. 64 KB over 50 ms caps it at about 10 Mbit/s, while 4 MB allows about 650 Mbit/s.
+// Benchmark before and after enabling it: on Linux, fixing the sizes disables the kernel autotuning,
+// which usually does better for mixed workloads, and larger buffers cost memory per connection.
+func WithSocketBufferSizes(read, write int) serverOption {
+	return func(srv *Server) {
+		srv.readBufferSize = read
+		srv.writeBufferSize = write
+	}
+}
+
+// WithListenConfig sets the listen config used to create the listeners of the server address
+// and of the additional listeners, e.g. to set socket options such as SO_REUSEPORT in its Control function,
+// or the TCP keep-alive period. It cannot be combined with WithListener,
+// in which case New returns ErrListenConfigConflict.
+func WithListenConfig(cfg *net.ListenConfig) serverOption {
+	return func(srv *Server) {
+		srv.listenConfig = cfg
+	}
+}
+
+// WithMiddleware adds middlewares to wrap the server handler inside New, instead of wrapping it manually.
+// The first middleware is the outermost one, and the middlewares of several WithMiddleware calls are appended.
+// The server's own middlewares, e.g. the shutdown responder, always wrap the user middlewares,
+// so the order is: server middlewares, user middlewares, handler.
+//
+// The middlewares wrap the final handler, i.e. the one built with WithHandlerBuilder if it is used,
+// or the handler of the server set with WithPreconfiguredServer, which replaces the handler passed to New.
+func WithMiddleware(mws ...func(http.Handler) http.Handler) serverOption {
+	return func(srv *Server) {
+		srv.middlewares = append(srv.middlewares, mws...)
+	}
+}
+
+// WithMaxDecompressedSize enables transparent decoding of gzip-encoded request bodies (Content-Encoding: gzip)
+// and caps the decoded size to n bytes, so that a small compressed body expanding to gigabytes cannot exhaust memory.
+// Handlers read the decoded body; reading past n bytes fails with ErrDecompressedBodyTooLarge,
+// and if the handler then returns without writing a response, 400 Bad Request is sent.
+// Malformed gzip bodies are rejected with 400 Bad Request before the handler is called.
+// A non-positive n disables the decoding, and encoded bodies are passed to the handler as is.
+// The decoding wraps all WithMiddleware middlewares, which see the decoded body without Content-Length.
+func WithMaxDecompressedSize(n int64) serverOption {
+	return func(srv *Server) {
+		srv.maxDecompressedSize = n
+	}
+}
+
+// WithListener sets an already created listener to serve on instead of listening on the server address,
+// e.g. a listener inherited from a parent process or created on a random port for tests.
+// The server address is still used for logging. The listener is closed when the server shuts down.
+func WithListener(ln net.Listener) serverOption {
+	return func(srv *Server) {
+		srv.listener = ln
+	}
+}
+
 // WithReadTimeout sets the maximum duration for reading the entire request, including the body.
 // This also includes the time spent reading the request header.
 // If the server does not receive a new request within this duration it will close the connection.
@@ -27,6 +140,86 @@ func WithReadTimeout(d time.Duration) serverOption {
 	}
 }
 
+// WithRequestTimeout sets a deadline on the context of every request, i.e. r.Context().
+// Handlers using context-aware libraries, e.g. database drivers, abort once the deadline is exceeded,
+// while the connection stays open, so the handler can still write a proper error response.
+// If the handler returns without writing any response after the deadline, the timeout response is sent,
+// by default 504 Gateway Timeout with a JSON body; see WithTimeoutResponse.
+// A duration of 0 means no deadline.
+//
+// Unlike WriteTimeout, which kills the connection without a response once the deadline passes,
+// and http.TimeoutHandler, which buffers the whole response and replies with 503 on timeout while
+// the handler keeps running in the background, the handler itself decides how to react to the deadline.
+func WithRequestTimeout(d time.Duration) serverOption {
+	return func(srv *Server) {
+		srv.requestTimeout = d
+	}
+}
+
+// WithResponseHeaders sets a fixed set of headers on every response of the server, e.g. X-Frame-Options,
+// which is simpler than writing a middleware for static headers. The headers are set before any handler
+// or middleware runs, so they apply to all the responses, including static files and error responses
+// of the server itself, e.g. the shutdown responder and the request timeout, while handlers can still
+// override or delete them. The option can be used multiple times; later values replace earlier ones.
+func WithResponseHeaders(headers map[string]string) serverOption {
+	return func(srv *Server) {
+		if srv.responseHeaders == nil {
+			srv.responseHeaders = make(http.Header, len(headers))
+		}
+		for key, value := range headers {
+			srv.responseHeaders.Set(key, value)
+		}
+	}
+}
+
+// WithTimeoutResponse sets the response sent by the timeout middlewares of the server when a request times out,
+// so that the error format is consistent with the rest of the API, e.g. a JSON body or an HTML page.
+// By default it is 504 Gateway Timeout with the body {"error":"request timeout"}.
+// It applies to WithRequestTimeout only, the one timeout which leaves the handler a chance to respond:
+// WithAbsoluteRequestTimeout closes the connection and WithHTTP2StreamTimeout resets the stream
+// once its write deadline passes, so no response can be written in either case.
+func WithTimeoutResponse(status int, contentType string, body []byte) serverOption {
+	return func(srv *Server) {
+		srv.timeoutResponse = &fixedResponse{
+			status:      status,
+			contentType: contentType,
+			body:        body,
+		}
+	}
+}
+
+// WithAbsoluteRequestTimeout sets a hard cap on the duration of every request, no matter what the handler does.
+// A watchdog closes the connection of a request which is still being handled after the duration,
+// so the client gets a connection error instead of a response, and logs the killed request.
+// Closing the connection cancels the request context; handlers ignoring it keep running until they return,
+// but their writes fail. A duration of 0 means no cap.
+//
+// It is a last resort against runaway requests; prefer WithRequestTimeout, which lets the handler respond in time,
+// and use this option with a larger duration as a safety net. Over HTTP/2 the connection is shared by
+// several requests, which are all aborted along with the one exceeding the cap.
+func WithAbsoluteRequestTimeout(d time.Duration) serverOption {
+	return func(srv *Server) {
+		srv.absoluteRequestTimeout = d
+	}
+}
+
+// WithHTTP2StreamTimeout sets a timeout for every HTTP/2 stream, i.e. request, on its own:
+// the read and write deadlines of the stream and the deadline of the request context are set
+// to the duration from the start of the request. A stream exceeding it is reset, and its handler's reads
+// and writes fail, while the other streams multiplexed over the same connection are not affected.
+// HTTP/1.x requests are not affected either; they keep using the connection-level timeouts.
+// The client of a reset stream gets a stream error, not the WithTimeoutResponse response.
+// A duration of 0 means no timeout.
+//
+// The connection-level WriteTimeout does not fit multiplexed connections, where it would bound streams
+// regardless of their own needs: when serving HTTP/2, set WriteTimeout to 0 and rely on this option instead,
+// or keep a larger WriteTimeout only for HTTP/1.x clients, since the stream deadlines take precedence over it.
+func WithHTTP2StreamTimeout(d time.Duration) serverOption {
+	return func(srv *Server) {
+		srv.streamTimeout = d
+	}
+}
+
 // WithReadHeaderTimeout sets the amount of time allowed to read request headers.
 // A duration of 0 means no timeout.
 func WithReadHeaderTimeout(d time.Duration) serverOption {
@@ -52,6 +245,27 @@ func WithIdleTimeout(d time.Duration) serverOption {
 	}
 }
 
+// WithConnStateLogging logs every connection state transition (new, active, idle, hijacked, closed)
+// with the remote address at debug level, e.g. to diagnose keep-alive and timeout issues.
+// It is verbose, so it is off by default, and it requires a logger supporting debug messages, e.g. *slog.Logger.
+// The ConnState hook of a preconfigured http.Server, if any, is still called.
+func WithConnStateLogging() serverOption {
+	return func(srv *Server) {
+		srv.connStateLogging = true
+	}
+}
+
+// WithMaxIdleConnections caps the number of idle keep-alive connections, which pile up after traffic bursts
+// and hold memory, since net/http does not limit them server-side. Once a connection becomes idle
+// and the cap is exceeded, the connections idle for the longest time are closed;
+// clients transparently reconnect on their next request. A non-positive number means no cap.
+// Reaped connections are logged at debug level if the logger supports it, e.g. *slog.Logger.
+func WithMaxIdleConnections(n int) serverOption {
+	return func(srv *Server) {
+		srv.maxIdleConns = n
+	}
+}
+
 // WithMaxHeaderBytes sets the maximum size of request headers.
 // This prevents attacks where an attacker sends a large header to consume server resources.
 // If zero, DefaultMaxHeaderBytes of 1MB is used.
@@ -61,6 +275,25 @@ func WithMaxHeaderBytes(n int) serverOption {
 	}
 }
 
+// WithMaxHeaderValueLength rejects requests with a header value longer than n bytes, e.g. an oversized cookie or JWT,
+// with 431 Request Header Fields Too Large, before they reach the handler. The rejected header name and length are logged.
+// This complements WithMaxHeaderBytes, which caps the total size of the headers, so that a single value
+// cannot silently consume the whole budget. Every value of a repeated header is checked on its own.
+// A non-positive n disables the limit, which is the default.
+func WithMaxHeaderValueLength(n int) serverOption {
+	return func(srv *Server) {
+		srv.maxHeaderValueLen = n
+	}
+}
+
+// WithDisableGeneralOptionsHandler disables the built-in handling of "OPTIONS *" requests by net/http,
+// passing them to the handler instead, which may reject them.
+func WithDisableGeneralOptionsHandler() serverOption {
+	return func(srv *Server) {
+		srv.httpServer.DisableGeneralOptionsHandler = true
+	}
+}
+
 // WithTLSConfig sets the TLS configuration to use when starting TLS.
 // If nil, the default configuration is used.
 // If non-nil, HTTP/2 support may not be enabled by default.
@@ -70,6 +303,14 @@ func WithTLSConfig(t *tls.Config) serverOption {
 	}
 }
 
+// WithStrictTLS makes Start fail with ErrTLSConfigIgnored, joined with ErrServerStart, if a TLS configuration with certificates is set.
+// Without this option such a misconfiguration is only logged and the server silently serves plain HTTP.
+func WithStrictTLS() serverOption {
+	return func(srv *Server) {
+		srv.strictTLS = true
+	}
+}
+
 // WithTLSNextProto sets a function to be called after a TLS handshake has been completed.
 // This is useful for protocols which require interaction immediately after the handshake.
 // If non-nil, HTTP/2 support may not be enabled by default.
@@ -80,11 +321,14 @@ func WithTLSNextProto(f func(*http.Server, *tls.Conn, http.Handler)) serverOptio
 	}
 }
 
-// WithErrorLog sets the error logger for the server.
-// If nil, the log package's standard logger is used.
+// WithErrorLog sets the logger for the internal errors of net/http, e.g. TLS handshake failures.
+// By default these errors are logged with the server logger, except the TLS handshake errors,
+// which the server logs itself with rate limiting instead.
+// If nil, the log package's standard logger is used, as net/http does by default.
 func WithErrorLog(l *log.Logger) serverOption {
 	return func(srv *Server) {
 		srv.httpServer.ErrorLog = l
+		srv.errorLogSet = true
 	}
 }
 
@@ -96,6 +340,146 @@ func WithGracefulShutdown(d time.Duration) serverOption {
 	}
 }
 
+// WithAdaptiveShutdownTimeout makes the graceful shutdown timeout scale with the load:
+// it is computed as base + perConn × the number of connections handling a request when the shutdown starts,
+// capped at max, so busy instances get more time to drain while idle ones stop fast.
+// The computed timeout is logged. It takes precedence over WithGracefulShutdown for the shutdown
+// initiated by a signal or the context cancellation; an explicit Stop call uses the given timeout.
+func WithAdaptiveShutdownTimeout(base, perConn, max time.Duration) serverOption {
+	return func(srv *Server) {
+		srv.adaptiveShutdown = &adaptiveShutdown{base: base, perConn: perConn, max: max}
+	}
+}
+
+// WithCriticalRequestDraining prioritizes the requests marked with MarkCritical during the graceful shutdown.
+// Once nonCriticalTimeout has passed since the shutdown started, the connections handling only non-critical requests
+// are closed, which cancels the request contexts and aborts the responses, while the critical requests get
+// the full shutdown timeout to complete. Idle connections are closed right away, as usual.
+// A nonCriticalTimeout not shorter than the shutdown timeout makes the option a no-op.
+//
+// Handlers should mark long-running requests, e.g. report generation or batch processing, as early as possible.
+// An HTTP/2 connection multiplexing several requests is kept open if any of them is critical.
+func WithCriticalRequestDraining(nonCriticalTimeout time.Duration) serverOption {
+	return func(srv *Server) {
+		srv.priorities = newPriorityTracker()
+		srv.nonCriticalTimeout = nonCriticalTimeout
+	}
+}
+
+// WithClientIPLogging adds the IP address of the client, resolved with ClientIP, as "client_ip"
+// to the server logs about a request, e.g. the one killed by the absolute request timeout.
+// The forwarding headers are used only if the immediate peer belongs to one of the trusted proxy prefixes.
+// Use WithAccessLogClientIP to do the same in the access log.
+func WithClientIPLogging(trustedProxies ...netip.Prefix) serverOption {
+	return func(srv *Server) {
+		srv.clientIPLogging = true
+		srv.trustedProxies = trustedProxies
+	}
+}
+
+// WithErrorRenderer sets the renderer of the error responses generated by the package for the requests
+// served by the server, e.g. 404 Not Found and 405 Method Not Allowed of the static handlers,
+// 413 Request Entity Too Large of RequireMaxContentLength, 503 Service Unavailable of the default
+// shutdown response and ConcurrencyLimitMiddleware, so that an API can render them consistently,
+// e.g. with JSONErrorRenderer. Explicitly configured responses, e.g. WithShutdownResponse
+// or the maintenance page, are sent as is. By default error responses are plain text.
+func WithErrorRenderer(fn ErrorRenderer) serverOption {
+	return func(srv *Server) {
+		srv.errorRenderer = fn
+	}
+}
+
+// WithShutdownNotify makes the server close the channel as soon as the shutdown begins, by Stop, Close,
+// a signal or the context cancellation, so that goroutines outside the server, e.g. a metrics flusher,
+// can react. Closing never blocks, so slow consumers cannot delay the shutdown, and all the receivers
+// are notified at once. The channel must not be closed or sent to by anyone else, nor shared between servers.
+// The option can be used multiple times to notify several channels.
+func WithShutdownNotify(ch chan<- struct{}) serverOption {
+	return func(srv *Server) {
+		if ch != nil {
+			srv.shutdownNotify = append(srv.shutdownNotify, ch)
+		}
+	}
+}
+
+// WithShutdownDelay makes the graceful shutdown wait for the given delay before the listeners are closed.
+// Meanwhile the server is already shutting down: ReadinessHandler fails, the readiness file is removed and
+// HTTP/1.x responses carry "Connection: close", but new connections and requests are still served as usual.
+// This gives load balancers and the Kubernetes endpoint controllers time to stop routing traffic to the instance,
+// so that no client hits a closed port. The shutdown timeout starts after the delay,
+// so the total shutdown takes up to the delay plus the timeout. By default there is no delay.
+func WithShutdownDelay(d time.Duration) serverOption {
+	return func(srv *Server) {
+		srv.shutdownDelay = d
+	}
+}
+
+// kubernetesShutdownDelay is the delay set by WithKubernetesGracefulShutdown.
+// It covers the propagation of the endpoint removal to kube-proxy and ingress controllers, which usually takes a few seconds.
+const kubernetesShutdownDelay = 5 * time.Second
+
+// WithKubernetesGracefulShutdown configures the best-practice shutdown sequence of a Kubernetes pod.
+// On SIGTERM, which the kubelet sends when the pod is deleted, the server:
+//
+//  1. flips ReadinessHandler to 503 Service Unavailable and removes the readiness file, if any,
+//     and starts sending "Connection: close" on HTTP/1.x responses (at 0s);
+//  2. keeps serving new requests for 5 seconds, while the pod is being removed from the endpoints
+//     of its services and the load balancers stop routing traffic to it (0s to 5s);
+//  3. closes the listeners and drains the active connections with the shutdown timeout
+//     set with WithGracefulShutdown, 5 seconds by default (5s to 10s);
+//  4. logs the number of the drained connections once done, or the requests which did not complete
+//     within the timeout before closing them (at 10s at the latest).
+//
+// The option is composed of WithShutdownDelay(5*time.Second) and WithInflightTracking,
+// so a later WithShutdownDelay overrides the delay. Mount ReadinessHandler as the readiness probe
+// of the container, and keep terminationGracePeriodSeconds of the pod (30 seconds by default)
+// above the delay plus the shutdown timeout, otherwise the kubelet kills the process with SIGKILL mid-drain.
+func WithKubernetesGracefulShutdown() serverOption {
+	return func(srv *Server) {
+		WithShutdownDelay(kubernetesShutdownDelay)(srv)
+		WithInflightTracking()(srv)
+	}
+}
+
+// WithShutdownPhases splits the graceful shutdown timeout into two phases.
+// The given fraction of the timeout, e.g. 0.8, is spent on the graceful shutdown.
+// If the server has not stopped by then, the in-flight requests are logged
+// and the remaining time is a guaranteed window for them to complete before the server is force closed.
+// The fraction must be greater than 0 and at most 1, otherwise New returns ErrInvalidShutdownPhases.
+// By default the whole timeout is spent on the graceful shutdown.
+func WithShutdownPhases(gracefulFraction float64) serverOption {
+	return func(srv *Server) {
+		srv.gracefulFraction = gracefulFraction
+	}
+}
+
+// WithForceQuitOnSecondSignal makes a second shutdown signal (SIGINT or SIGTERM) received during the graceful shutdown
+// close the server immediately, without waiting for the active requests to complete.
+// This lets impatient operators press Ctrl+C twice instead of resorting to SIGKILL.
+// By default further signals are ignored once the graceful shutdown has started.
+func WithForceQuitOnSecondSignal() serverOption {
+	return func(srv *Server) {
+		srv.forceQuitOnSecondSignal = true
+	}
+}
+
+// WithSignalHandler sets the action of the server on the given signal, e.g. to drain fully on SIGTERM,
+// which an orchestrator sends to stop a container, but close immediately on SIGINT for fast feedback on Ctrl+C:
+//
+//	httpserver.WithSignalHandler(os.Interrupt, httpserver.ImmediateClose)
+//
+// Both SIGINT and SIGTERM trigger GracefulDrain by default. Other signals, e.g. SIGHUP, are subscribed to
+// as well when set, so they shut the server down too. The option can be used multiple times, once per signal.
+// With WithForceQuitOnSecondSignal, any second signal during a graceful drain still closes the server immediately.
+func WithSignalHandler(sig os.Signal, action ShutdownAction) serverOption {
+	return func(srv *Server) {
+		if srv.signalActions == nil {
+			srv.signalActions = make(map[os.Signal]ShutdownAction)
+		}
+		srv.signalActions[sig] = action
+	}
+}
+
 // WithLogger sets the logger for the server.
 // If nil, the log package's standard logger is used.
 // If you want to use a structured logger, consider using the slog package.
@@ -104,3 +488,158 @@ func WithLogger(l Logger) serverOption {
 		srv.log = l
 	}
 }
+
+// WithLogFields sets key-value pairs which are added to every lifecycle log message of the server,
+// e.g. WithLogFields("service", "api", "env", "production").
+// This is handy when the logger is not already enriched with the application fields.
+// The option can be used multiple times, the fields are accumulated.
+func WithLogFields(keyvals ...interface{}) serverOption {
+	return func(srv *Server) {
+		srv.logFields = append(srv.logFields, keyvals...)
+	}
+}
+
+// WithAcceptErrorHandler sets a policy hook which is called when accepting a new connection fails.
+// The handler returns true if the error is recoverable and the server should keep serving,
+// in which case accepting is retried with an exponential backoff (up to 1 second).
+// If the handler returns false, the server stops with the error.
+// Both cases are logged with the server logger.
+// If not set, accept errors are handled by net/http, which retries only temporary errors.
+func WithAcceptErrorHandler(h func(error) bool) serverOption {
+	return func(srv *Server) {
+		srv.acceptErrorHandler = h
+	}
+}
+
+// WithRequestStats enables counting of the handled requests by the response status class,
+// reported in ServerStats.Requests by Stats and StatsHandler. It wraps every response writer
+// to capture the status, which costs an allocation per request and hides the optional interfaces
+// of the writer not known to the package, e.g. http.Pusher, so it is disabled by default.
+func WithRequestStats() serverOption {
+	return func(srv *Server) {
+		srv.requestStats = true
+	}
+}
+
+// WithInflightTracking enables tracking of the requests which are being handled by the server.
+// If the graceful shutdown times out, the method, path and start time of every request
+// which failed to complete are logged, which helps to diagnose why the drain did not finish.
+// Up to 1000 requests are tracked individually, the rest are only counted.
+func WithInflightTracking() serverOption {
+	return func(srv *Server) {
+		srv.inflight = newInflightTracker()
+	}
+}
+
+// WithAdditionalListener makes the server listen on one more address, e.g. to serve both HTTP and HTTPS,
+// or to serve an admin handler (pprof, metrics, health) on a separate port.
+// If handler is nil, the server handler is used, otherwise requests on this address are served by the given handler.
+// Such a handler is wrapped by the server middlewares, e.g. the shutdown responder,
+// but not by the ones set with WithMiddleware, which belong to the server handler.
+// If tlsConfig is not nil, connections on this address are served over TLS using it.
+// All listeners share the lifecycle of the server, so they are started by Start
+// and drained together on shutdown. The option can be used multiple times.
+func WithAdditionalListener(addr string, handler http.Handler, tlsConfig *tls.Config) serverOption {
+	return func(srv *Server) {
+		if tlsConfig != nil && len(tlsConfig.NextProtos) == 0 {
+			// Advertise HTTP/2 support as http.Server.ServeTLS does
+			tlsConfig = tlsConfig.Clone()
+			tlsConfig.NextProtos = []string{"h2", "http/1.1"}
+		}
+		srv.listeners = append(srv.listeners, additionalListener{
+			addr:      addr,
+			tlsConfig: tlsConfig,
+			handler:   handler,
+		})
+	}
+}
+
+// WithReadinessFile makes the server create an empty file at the given path once all its listeners are bound,
+// and delete it at the start of the shutdown, before the in-flight requests are drained,
+// so that external scripts of legacy orchestration can detect the state transitions by the file presence.
+// The file is also deleted if the server fails to start or stops for any other reason.
+// Errors creating or deleting the file are logged and do not affect the server lifecycle.
+//
+// The file is independent of HealthHandler, which keeps reporting the results of the health checks
+// during the shutdown; the file is removed as soon as the server stops accepting new work.
+func WithReadinessFile(path string) serverOption {
+	return func(srv *Server) {
+		srv.readinessFile = path
+	}
+}
+
+// WithHealthCheck registers a named health check which is run by the handler returned by HealthHandler.
+// The option can be used multiple times to register several checks.
+func WithHealthCheck(name string, check HealthCheckFunc) serverOption {
+	return func(srv *Server) {
+		srv.healthChecks = append(srv.healthChecks, &healthCheck{name: name, check: check})
+	}
+}
+
+// WithStartupValidation registers a function which checks the runtime prerequisites of the server,
+// e.g. that the secrets are present or the database is reachable.
+// The validators run synchronously in Start and StartTLS, in the order they are registered,
+// before any listener is opened, so the server never accepts connections if a prerequisite is missing.
+// They also complete before the server reports ready: the WithReadinessFile file is written,
+// and ReadinessHandler is reachable, only once all of them have passed and the listeners are open.
+// The first error aborts the startup and is returned wrapped in ErrServerStart.
+// The option can be used multiple times to register several validators.
+func WithStartupValidation(fn func() error) serverOption {
+	return func(srv *Server) {
+		if fn != nil {
+			srv.startupValidators = append(srv.startupValidators, fn)
+		}
+	}
+}
+
+// WithShutdownOnUnhealthy makes the server shut down gracefully once the named health check,
+// registered with WithHealthCheck, has been failing continuously for the given duration.
+// This turns a persistent dependency failure into a clean restart by the orchestrator
+// instead of serving errors indefinitely. The check is run periodically, at a quarter of the duration,
+// and its results are shared with HealthHandler. Start returns ErrServerUnhealthy in this case.
+func WithShutdownOnUnhealthy(check string, after time.Duration) serverOption {
+	return func(srv *Server) {
+		srv.unhealthyCheck = check
+		srv.unhealthyAfter = after
+	}
+}
+
+// WithShutdownResponder makes the server reject requests arriving while it is shutting down,
+// e.g. on keep-alive connections, with 503 Service Unavailable and the Retry-After header,
+// so that clients retry on another instance. Requests which started before the shutdown are completed as usual.
+// The response can be customized with WithShutdownResponse.
+func WithShutdownResponder() serverOption {
+	return func(srv *Server) {
+		if srv.shutdownResponse == nil {
+			srv.shutdownResponse = defaultShutdownResponse
+		}
+	}
+}
+
+// WithShutdownSafeResponses makes the server replace the response of a request which was already being handled
+// when the shutdown began, but has not started writing yet, with the shutdown response (see WithShutdownResponse),
+// instead of beginning a write which may be cut off by the shutdown timeout. The client gets a clear 503
+// Service Unavailable with the Retry-After header rather than a truncated response, e.g. during deploys.
+// Writes of the replaced response body fail with ErrResponseAborted, so streaming handlers can stop early.
+//
+// Only idempotent requests (GET, HEAD, OPTIONS, TRACE, PUT, DELETE) are affected, since the client
+// may retry them after the handler has already done its work; other responses are sent as usual.
+// Responses which have already started writing cannot be rescued and may still be truncated at the timeout.
+func WithShutdownSafeResponses() serverOption {
+	return func(srv *Server) {
+		srv.shutdownSafeResponses = true
+	}
+}
+
+// WithShutdownResponse sets the response of the shutdown responder, e.g. a JSON body or an HTML page,
+// and enables the responder. The Retry-After header is always sent.
+// By default the responder sends a plain-text 503 Service Unavailable.
+func WithShutdownResponse(status int, contentType string, body []byte) serverOption {
+	return func(srv *Server) {
+		srv.shutdownResponse = &fixedResponse{
+			status:      status,
+			contentType: contentType,
+			body:        body,
+		}
+	}
+}
@@ -1,10 +1,26 @@
 package httpserver_test
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	stdlog "log"
+	"math"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
@@ -96,3 +112,1663 @@ func TestErrorServerStart(t *testing.T) {
 	_, err = http.Get(fmt.Sprintf("http://%s", listenAddr))
 	require.Error(t, err, "Expected error after server shutdown")
 }
+
+func TestTLSValidation(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{{}}}
+
+	t.Run("start with TLS certificates in strict mode", func(t *testing.T) {
+		server, err := httpserver.New("localhost:9999", handler,
+			httpserver.WithTLSConfig(tlsConfig),
+			httpserver.WithStrictTLS(),
+		)
+		require.NoError(t, err)
+		err = server.Start(context.Background())
+		require.ErrorIs(t, err, httpserver.ErrTLSConfigIgnored)
+		require.ErrorIs(t, err, httpserver.ErrServerStart)
+	})
+
+	t.Run("start TLS without certificates", func(t *testing.T) {
+		server, err := httpserver.New("localhost:9999", handler)
+		require.NoError(t, err)
+		err = server.StartTLS(context.Background(), "", "")
+		require.ErrorIs(t, err, httpserver.ErrNoTLSCertificate)
+		require.ErrorIs(t, err, httpserver.ErrServerStart)
+	})
+}
+
+func TestAdditionalListener(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintln(w, "Hello, World!")
+	})
+	server, err := httpserver.New("localhost:9998", handler,
+		httpserver.WithAdditionalListener("localhost:9997", nil, nil),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.Start(ctx)
+	}()
+	time.Sleep(500 * time.Millisecond)
+
+	for _, addr := range []string{"localhost:9998", "localhost:9997"} {
+		resp, err := http.Get(fmt.Sprintf("http://%s", addr))
+		require.NoError(t, err, addr)
+		require.Equal(t, http.StatusOK, resp.StatusCode, addr)
+		resp.Body.Close()
+	}
+
+	cancel()
+	select {
+	case err := <-serverErr:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Server shutdown timed out")
+	}
+
+	for _, addr := range []string{"localhost:9998", "localhost:9997"} {
+		_, err = http.Get(fmt.Sprintf("http://%s", addr))
+		require.Error(t, err, addr)
+	}
+}
+
+func TestAdditionalListenerHandler(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	// Reserve a free port for the admin listener
+	adminLn, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	adminAddr := adminLn.Addr().String()
+	require.NoError(t, adminLn.Close())
+
+	public := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "public")
+	})
+	admin := http.NewServeMux()
+	admin.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "metrics")
+	})
+
+	server, err := httpserver.New(ln.Addr().String(), public,
+		httpserver.WithListener(ln),
+		httpserver.WithAdditionalListener(adminAddr, admin, nil),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.Start(ctx)
+	}()
+
+	get := func(url string) (int, string) {
+		var resp *http.Response
+		require.Eventually(t, func() bool {
+			resp, err = http.Get(url)
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	code, body := get("http://" + ln.Addr().String() + "/metrics")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "public", body)
+
+	code, body = get("http://" + adminAddr + "/metrics")
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "metrics", body)
+
+	code, _ = get("http://" + adminAddr + "/")
+	require.Equal(t, http.StatusNotFound, code)
+
+	cancel()
+	select {
+	case err := <-serverErr:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Server shutdown timed out")
+	}
+
+	_, err = http.Get("http://" + adminAddr)
+	require.Error(t, err)
+}
+
+func TestServerFromContext(t *testing.T) {
+	var server *httpserver.Server
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := httpserver.ServerFromContext(r.Context())
+		if !ok || s != server {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = fmt.Fprint(w, s.ShuttingDown())
+	})
+
+	server, err := httpserver.New("localhost:9996", handler)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = server.Start(ctx)
+	}()
+	time.Sleep(500 * time.Millisecond)
+
+	resp, err := http.Get("http://localhost:9996")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "false", string(body))
+
+	_, ok := httpserver.ServerFromContext(context.Background())
+	require.False(t, ok)
+}
+
+func TestCloseIdleConnections(t *testing.T) {
+	var connections atomic.Int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintln(w, "Hello, World!")
+	})
+	server, err := httpserver.New("localhost:9995", handler,
+		httpserver.WithPreconfiguredServer(&http.Server{
+			Addr:    "localhost:9995",
+			Handler: handler,
+			ConnState: func(c net.Conn, state http.ConnState) {
+				if state == http.StateNew {
+					connections.Add(1)
+				}
+			},
+		}),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = server.Start(ctx)
+	}()
+	time.Sleep(500 * time.Millisecond)
+
+	client := &http.Client{Transport: &http.Transport{}}
+	get := func() {
+		resp, err := client.Get("http://localhost:9995")
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	// Keep-alive connection is reused
+	get()
+	get()
+	require.EqualValues(t, 1, connections.Load())
+
+	// Idle connection is closed, so the client has to reconnect
+	server.CloseIdleConnections()
+	time.Sleep(100 * time.Millisecond)
+	get()
+	require.EqualValues(t, 2, connections.Load())
+}
+
+func TestMaxIdleConnections(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	server, err := httpserver.New(ln.Addr().String(), http.NotFoundHandler(),
+		httpserver.WithListener(ln),
+		httpserver.WithMaxIdleConnections(2),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = server.Start(ctx) }()
+
+	// Open keep-alive connections one by one, so that the first ones are the oldest idle
+	var conns []net.Conn
+	for i := 0; i < 5; i++ {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		require.NoError(t, err)
+		defer conn.Close()
+		_, err = fmt.Fprint(conn, "GET / HTTP/1.1\r\nHost: localhost\r\n\r\n")
+		require.NoError(t, err)
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		conns = append(conns, conn)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	require.Eventually(t, func() bool {
+		return server.Stats().IdleConnections == 2
+	}, time.Second, 10*time.Millisecond)
+
+	// The oldest connections are closed by the server, the newest are kept open
+	for i, conn := range conns {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+		_, err := conn.Read(make([]byte, 1))
+		if i < 3 {
+			require.ErrorIs(t, err, io.EOF, "connection %d", i)
+		} else {
+			require.ErrorIs(t, err, os.ErrDeadlineExceeded, "connection %d", i)
+		}
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	server, err := httpserver.New(ln.Addr().String(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "ok")
+	}), httpserver.WithListener(ln))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() { stopped <- server.Start(ctx) }()
+
+	url := "http://" + ln.Addr().String()
+	keepAlive := &http.Client{}
+	require.Eventually(t, func() bool {
+		resp, err := keepAlive.Get(url)
+		if err != nil {
+			return false
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return true
+	}, time.Second, 10*time.Millisecond)
+
+	server.Pause()
+
+	// Existing connections are kept alive
+	resp, err := keepAlive.Get(url)
+	require.NoError(t, err)
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	// New connections block until resumed
+	fresh := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	done := make(chan error, 1)
+	go func() {
+		resp, err := fresh.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("request completed while paused: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	server.Resume()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("request blocked after resume")
+	}
+
+	// Shutdown is not blocked by the pause
+	server.Pause()
+	cancel()
+	select {
+	case err := <-stopped:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("shutdown blocked while paused")
+	}
+}
+
+func TestConnStateLogging(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	var chained atomic.Int32
+	log := &httpserver.MemoryLogger{}
+	server, err := httpserver.New(ln.Addr().String(), nil,
+		httpserver.WithPreconfiguredServer(&http.Server{
+			Handler: http.NotFoundHandler(),
+			ConnState: func(c net.Conn, state http.ConnState) {
+				chained.Add(1)
+			},
+		}),
+		httpserver.WithListener(ln),
+		httpserver.WithLogger(log),
+		httpserver.WithConnStateLogging(),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- server.Start(ctx) }()
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	var resp *http.Response
+	require.Eventually(t, func() bool {
+		resp, err = client.Get("http://" + ln.Addr().String())
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	resp.Body.Close()
+
+	cancel()
+	require.NoError(t, <-stopped)
+
+	var states []string
+	for _, e := range log.Find("connection state changed") {
+		require.Equal(t, httpserver.LevelDebug, e.Level)
+		state, _ := e.Field("state")
+		states = append(states, state.(string))
+		addr, _ := e.Field("remote_addr")
+		require.NotEmpty(t, addr)
+	}
+	require.Equal(t, []string{"new", "active", "closed"}, states)
+	require.EqualValues(t, 3, chained.Load())
+}
+
+func TestShutdownOnUnhealthy(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	server, err := httpserver.New("localhost:9994", handler,
+		httpserver.WithHealthCheck("db", func(ctx context.Context) error {
+			if !healthy.Load() {
+				return errors.New("connection refused")
+			}
+			return nil
+		}),
+		httpserver.WithShutdownOnUnhealthy("db", 200*time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.Start(context.Background())
+	}()
+	time.Sleep(500 * time.Millisecond)
+
+	// Health handler reports the failing check
+	healthy.Store(false)
+	rec := httptest.NewRecorder()
+	server.HealthHandler()(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.JSONEq(t, `{"status":"fail","checks":{"db":"connection refused"}}`, rec.Body.String())
+
+	select {
+	case err := <-serverErr:
+		require.ErrorIs(t, err, httpserver.ErrServerUnhealthy)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Server did not shut down")
+	}
+}
+
+func TestHandlerBuilder(t *testing.T) {
+	var built *httpserver.Server
+	server, err := httpserver.New("localhost:9999", nil,
+		httpserver.WithHandlerBuilder(func(s *httpserver.Server) http.Handler {
+			built = s
+			return http.NotFoundHandler()
+		}),
+	)
+	require.NoError(t, err)
+	require.Same(t, server, built)
+
+	_, err = httpserver.New("localhost:9999", nil)
+	require.ErrorIs(t, err, httpserver.ErrNilHandler)
+
+	_, err = httpserver.New("localhost:9999", nil,
+		httpserver.WithHandlerBuilder(func(s *httpserver.Server) http.Handler { return nil }),
+	)
+	require.ErrorIs(t, err, httpserver.ErrNilHandler)
+}
+
+func TestRunDefault(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	http.HandleFunc("/run-default", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "default mux")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- httpserver.RunDefault(ctx, addr)
+	}()
+
+	var body []byte
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(fmt.Sprintf("http://%s/run-default", addr))
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		body, err = io.ReadAll(resp.Body)
+		return err == nil && resp.StatusCode == http.StatusOK
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, "default mux", string(body))
+
+	cancel()
+	select {
+	case err := <-serverErr:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Server shutdown timed out")
+	}
+}
+
+func TestRunListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintln(w, "Hello, World!")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- httpserver.RunListener(ctx, ln, handler)
+	}()
+
+	resp, err := http.Get(fmt.Sprintf("http://%s", addr))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+
+	cancel()
+	select {
+	case err := <-serverErr:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Server shutdown timed out")
+	}
+
+	// The listener is closed on shutdown
+	_, err = http.Get(fmt.Sprintf("http://%s", addr))
+	require.Error(t, err)
+}
+
+func TestTLSHandshakeFailures(t *testing.T) {
+	certPEM, keyPEM := generateTestCert(t)
+	tlsConfig, err := httpserver.TLSConfigFromPEM(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	server, err := httpserver.New("localhost:9993", handler, httpserver.WithTLSConfig(tlsConfig))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = server.StartTLS(ctx, "", "")
+	}()
+	time.Sleep(500 * time.Millisecond)
+
+	// The client does not trust the self-signed certificate
+	for i := 0; i < 3; i++ {
+		_, err = http.Get("https://localhost:9993")
+		require.Error(t, err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	require.EqualValues(t, 3, server.Stats().TLSHandshakeFailures)
+}
+
+func TestUptime(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	server, err := httpserver.New(ln.Addr().String(), http.NotFoundHandler(), httpserver.WithListener(ln))
+	require.NoError(t, err)
+	require.True(t, server.StartedAt().IsZero())
+	require.Zero(t, server.Uptime())
+
+	before := time.Now()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = server.Start(ctx) }()
+
+	require.Eventually(t, func() bool {
+		return !server.StartedAt().IsZero()
+	}, time.Second, time.Millisecond)
+	require.False(t, server.StartedAt().Before(before))
+
+	uptime := server.Uptime()
+	require.Positive(t, uptime)
+	time.Sleep(10 * time.Millisecond)
+	require.Greater(t, server.Uptime(), uptime)
+}
+
+func TestStatsHandler(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+	server, err := httpserver.New(ln.Addr().String(), mux, httpserver.WithListener(ln), httpserver.WithRequestStats())
+	require.NoError(t, err)
+	mux.Handle("/stats", server.StatsHandler())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = server.Start(ctx) }()
+
+	get := func(path string) *http.Response {
+		var resp *http.Response
+		require.Eventually(t, func() bool {
+			resp, err = http.Get("http://" + ln.Addr().String() + path)
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+		return resp
+	}
+	get("/ok").Body.Close()
+	get("/ok").Body.Close()
+	get("/missing").Body.Close()
+
+	resp := get("/stats")
+	defer resp.Body.Close()
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var stats struct {
+		httpserver.ServerStats
+		UptimeSeconds float64 `json:"uptime_seconds"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&stats))
+	require.Equal(t, map[string]uint64{"1xx": 0, "2xx": 2, "3xx": 0, "4xx": 1, "5xx": 0}, stats.Requests)
+	require.EqualValues(t, 1, stats.InflightRequests, "the stats request itself")
+	require.EqualValues(t, 1, stats.ActiveConnections)
+	require.Greater(t, stats.UptimeSeconds, 0.0)
+
+	t.Run("requests are not counted by default", func(t *testing.T) {
+		server, err := httpserver.New("localhost:0", http.NotFoundHandler())
+		require.NoError(t, err)
+		require.Nil(t, server.Stats().Requests)
+		require.NotContains(t, server.Config().Features, "WithRequestStats")
+	})
+}
+
+func TestShutdownPhases(t *testing.T) {
+	t.Run("invalid fraction", func(t *testing.T) {
+		for _, f := range []float64{0, -0.5, 1.5} {
+			_, err := httpserver.New(":0", http.NotFoundHandler(), httpserver.WithShutdownPhases(f))
+			require.ErrorIs(t, err, httpserver.ErrInvalidShutdownPhases)
+		}
+	})
+
+	stop := func(t *testing.T, fraction float64) error {
+		ln, err := net.Listen("tcp", "localhost:0")
+		require.NoError(t, err)
+
+		started := make(chan struct{})
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(300 * time.Millisecond)
+			w.WriteHeader(http.StatusOK)
+		})
+		srv, err := httpserver.New(ln.Addr().String(), handler,
+			httpserver.WithListener(ln),
+			httpserver.WithInflightTracking(),
+			httpserver.WithShutdownPhases(fraction),
+		)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() { _ = srv.Start(ctx) }()
+
+		go func() {
+			resp, err := http.Get("http://" + ln.Addr().String())
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+		<-started
+
+		return srv.Stop(context.Background(), 400*time.Millisecond)
+	}
+
+	t.Run("requests complete in the second phase", func(t *testing.T) {
+		require.NoError(t, stop(t, 0.5))
+	})
+
+	t.Run("single phase", func(t *testing.T) {
+		require.Error(t, stop(t, 1))
+	})
+}
+
+func TestRequestTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok := r.Context().Deadline()
+		if !ok || time.Until(deadline) > 50*time.Millisecond {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		<-r.Context().Done()
+		if r.URL.Path == "/custom" {
+			http.Error(w, "aborted", http.StatusServiceUnavailable)
+		}
+	})
+	srv, err := httpserver.New(ln.Addr().String(), handler,
+		httpserver.WithListener(ln),
+		httpserver.WithRequestTimeout(50*time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Start(ctx) }()
+
+	client := &http.Client{Transport: &http.Transport{}}
+	for path, expected := range map[string]int{
+		"/":       http.StatusGatewayTimeout,
+		"/custom": http.StatusServiceUnavailable,
+	} {
+		resp, err := client.Get("http://" + ln.Addr().String() + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, expected, resp.StatusCode, path)
+	}
+}
+
+func TestTimeoutResponse(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+
+	tests := []struct {
+		name        string
+		opt         func(*httpserver.Server)
+		status      int
+		contentType string
+		body        string
+	}{
+		{
+			name:        "default",
+			opt:         func(*httpserver.Server) {},
+			status:      http.StatusGatewayTimeout,
+			contentType: "application/json",
+			body:        `{"error":"request timeout"}`,
+		},
+		{
+			name:        "custom",
+			opt:         httpserver.WithTimeoutResponse(http.StatusServiceUnavailable, "application/problem+json", []byte(`{"title":"timeout"}`)),
+			status:      http.StatusServiceUnavailable,
+			contentType: "application/problem+json",
+			body:        `{"title":"timeout"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "localhost:0")
+			require.NoError(t, err)
+
+			srv, err := httpserver.New(ln.Addr().String(), handler,
+				httpserver.WithListener(ln),
+				httpserver.WithRequestTimeout(50*time.Millisecond),
+				tt.opt,
+			)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() { _ = srv.Start(ctx) }()
+
+			resp, err := http.Get("http://" + ln.Addr().String())
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, tt.status, resp.StatusCode)
+			require.Equal(t, tt.contentType, resp.Header.Get("Content-Type"))
+			require.JSONEq(t, tt.body, string(body))
+		})
+	}
+}
+
+func TestResponseHeaders(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "ok")
+	})
+	mux.HandleFunc("/override", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Frame-Options", "SAMEORIGIN")
+		w.Header().Del("X-Tracking")
+	})
+	mux.Handle("/static/", httpserver.StaticHandler("/static", testStaticFS(), time.Hour))
+
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	server, err := httpserver.New(ln.Addr().String(), mux,
+		httpserver.WithListener(ln),
+		httpserver.WithResponseHeaders(map[string]string{
+			"X-Frame-Options": "DENY",
+			"x-tracking":      "v1",
+		}),
+		httpserver.WithResponseHeaders(map[string]string{"X-Tracking": "v2"}),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = server.Start(ctx) }()
+
+	get := func(path string) *http.Response {
+		var resp *http.Response
+		require.Eventually(t, func() bool {
+			resp, err = http.Get("http://" + ln.Addr().String() + path)
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+		resp.Body.Close()
+		return resp
+	}
+
+	for _, path := range []string{"/", "/static/app.js", "/static/missing.js"} {
+		resp := get(path)
+		require.Equal(t, "DENY", resp.Header.Get("X-Frame-Options"), path)
+		require.Equal(t, "v2", resp.Header.Get("X-Tracking"), path)
+	}
+
+	resp := get("/override")
+	require.Equal(t, "SAMEORIGIN", resp.Header.Get("X-Frame-Options"))
+	require.Empty(t, resp.Header.Values("X-Tracking"))
+}
+
+func TestAbsoluteRequestTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	handlerDone := make(chan string, 2)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() { handlerDone <- r.URL.Path }()
+		if r.URL.Path == "/fast" {
+			return
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+		_, _ = w.Write([]byte("too late"))
+	})
+
+	logger := &httpserver.MemoryLogger{}
+	server, err := httpserver.New(ln.Addr().String(), handler,
+		httpserver.WithListener(ln),
+		httpserver.WithLogger(logger),
+		httpserver.WithAbsoluteRequestTimeout(100*time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = server.Start(ctx) }()
+
+	var resp *http.Response
+	require.Eventually(t, func() bool {
+		resp, err = http.Get("http://" + ln.Addr().String() + "/fast")
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "/fast", <-handlerDone)
+
+	// A fresh connection, so that the client does not retry the request on a reused one
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	start := time.Now()
+	_, err = client.Get("http://" + ln.Addr().String() + "/slow")
+	require.Error(t, err)
+	require.Less(t, time.Since(start), time.Second)
+
+	require.Equal(t, "/slow", <-handlerDone)
+	entries := logger.Find("request exceeded the absolute timeout, closing the connection")
+	require.Len(t, entries, 1)
+	path, _ := entries[0].Field("path")
+	require.Equal(t, "/slow", path)
+}
+
+func TestReadinessFile(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "ready")
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+	server, err := httpserver.New(ln.Addr().String(), handler,
+		httpserver.WithListener(ln),
+		httpserver.WithReadinessFile(path),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() { stopped <- server.Start(ctx) }()
+
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+
+	// The file is removed at the start of the shutdown, while the request is still in flight
+	cancel()
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return errors.Is(err, os.ErrNotExist)
+	}, time.Second, 10*time.Millisecond)
+
+	close(release)
+	require.NoError(t, <-stopped)
+}
+
+func TestMiddleware(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	var order []string
+	mw := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	})
+
+	srv, err := httpserver.New(ln.Addr().String(), handler,
+		httpserver.WithListener(ln),
+		httpserver.WithShutdownResponder(),
+		httpserver.WithMiddleware(mw("first"), mw("second")),
+		httpserver.WithMiddleware(mw("third")),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = srv.Start(ctx) }()
+
+	resp, err := http.Get("http://" + ln.Addr().String())
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, []string{"first", "second", "third", "handler"}, order)
+}
+
+func TestListenConfig(t *testing.T) {
+	t.Run("conflict with listener", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "localhost:0")
+		require.NoError(t, err)
+		defer ln.Close()
+
+		_, err = httpserver.New(ln.Addr().String(), http.NotFoundHandler(),
+			httpserver.WithListener(ln),
+			httpserver.WithListenConfig(&net.ListenConfig{}),
+		)
+		require.ErrorIs(t, err, httpserver.ErrListenConfigConflict)
+	})
+
+	var controlled atomic.Int32
+	lc := &net.ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			controlled.Add(1)
+			return nil
+		},
+	}
+	server, err := httpserver.New("localhost:9996", http.NotFoundHandler(), httpserver.WithListenConfig(lc))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.Start(ctx)
+	}()
+
+	require.Eventually(t, func() bool {
+		resp, err := http.Get("http://localhost:9996")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusNotFound
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, int32(1), controlled.Load())
+
+	cancel()
+	require.NoError(t, <-serverErr)
+}
+
+func TestSetHandler(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	v1 := http.NewServeMux()
+	v1.HandleFunc("/v1", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "v1")
+	})
+	v2 := http.NewServeMux()
+	v2.HandleFunc("/v2", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "v2")
+	})
+
+	var wrapped atomic.Int32
+	server, err := httpserver.New(ln.Addr().String(), v1,
+		httpserver.WithListener(ln),
+		httpserver.WithMiddleware(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				wrapped.Add(1)
+				next.ServeHTTP(w, r)
+			})
+		}),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = server.Start(ctx) }()
+
+	get := func(path string) int {
+		var resp *http.Response
+		require.Eventually(t, func() bool {
+			resp, err = http.Get("http://" + ln.Addr().String() + path)
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	require.Equal(t, http.StatusOK, get("/v1"))
+	require.Equal(t, http.StatusNotFound, get("/v2"))
+
+	server.SetHandler(v2)
+	server.SetHandler(nil)
+	require.Equal(t, http.StatusNotFound, get("/v1"))
+	require.Equal(t, http.StatusOK, get("/v2"))
+	require.Equal(t, int32(4), wrapped.Load())
+}
+
+func TestStartupValidation(t *testing.T) {
+	errMissingSecret := errors.New("missing secret")
+
+	var calls []string
+	server, err := httpserver.New("localhost:9995", http.NotFoundHandler(),
+		httpserver.WithStartupValidation(func() error {
+			calls = append(calls, "first")
+			return nil
+		}),
+		httpserver.WithStartupValidation(func() error {
+			calls = append(calls, "second")
+			return errMissingSecret
+		}),
+		httpserver.WithStartupValidation(func() error {
+			calls = append(calls, "third")
+			return nil
+		}),
+	)
+	require.NoError(t, err)
+
+	err = server.Start(context.Background())
+	require.ErrorIs(t, err, httpserver.ErrServerStart)
+	require.ErrorIs(t, err, errMissingSecret)
+	require.Equal(t, []string{"first", "second"}, calls)
+
+	// The listener has never been opened
+	ln, err := net.Listen("tcp", "localhost:9995")
+	require.NoError(t, err)
+	require.NoError(t, ln.Close())
+}
+
+func TestCriticalRequestDraining(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	started := make(chan struct{}, 2)
+	aborted := make(chan struct{})
+	var marked atomic.Bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/report", func(w http.ResponseWriter, r *http.Request) {
+		marked.Store(httpserver.MarkCritical(r))
+		started <- struct{}{}
+		time.Sleep(300 * time.Millisecond)
+		_, _ = fmt.Fprint(w, "report")
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-r.Context().Done()
+		close(aborted)
+	})
+
+	server, err := httpserver.New(ln.Addr().String(), mux,
+		httpserver.WithListener(ln),
+		httpserver.WithCriticalRequestDraining(50*time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = server.Start(ctx) }()
+
+	url := "http://" + ln.Addr().String()
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	report := make(chan string, 1)
+	go func() {
+		resp, err := client.Get(url + "/report")
+		if err != nil {
+			report <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		report <- string(body)
+	}()
+	slowErr := make(chan error, 1)
+	go func() {
+		resp, err := client.Get(url + "/slow")
+		if err == nil {
+			resp.Body.Close()
+		}
+		slowErr <- err
+	}()
+	<-started
+	<-started
+
+	start := time.Now()
+	stopped := make(chan error, 1)
+	go func() { stopped <- server.Stop(context.Background(), 2*time.Second) }()
+
+	// The non-critical request is aborted early, the critical one completes
+	select {
+	case <-aborted:
+		require.Less(t, time.Since(start), time.Second)
+	case <-time.After(time.Second):
+		t.Fatal("non-critical request was not aborted")
+	}
+	require.Error(t, <-slowErr)
+	require.Equal(t, "report", <-report)
+	require.True(t, marked.Load())
+	require.NoError(t, <-stopped)
+
+	t.Run("without the option", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		require.False(t, httpserver.MarkCritical(req))
+	})
+}
+
+func TestHTTP2StreamTimeout(t *testing.T) {
+	certPEM, keyPEM := generateTestCert(t)
+	tlsConfig, err := httpserver.TLSConfigFromPEM(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	slowCtxErr := make(chan error, 1)
+	var hasDeadline atomic.Bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		_, ok := r.Context().Deadline()
+		hasDeadline.Store(ok)
+		<-r.Context().Done()
+		// Either the context deadline or the stream reset, whichever comes first
+		slowCtxErr <- r.Context().Err()
+		// A handler writing past the deadline gets the stream reset
+		time.Sleep(50 * time.Millisecond)
+		_, _ = fmt.Fprint(w, "too late")
+	})
+	mux.HandleFunc("/fast", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, r.Proto)
+	})
+
+	server, err := httpserver.New(ln.Addr().String(), mux,
+		httpserver.WithListener(ln),
+		httpserver.WithTLSConfig(tlsConfig),
+		httpserver.WithWriteTimeout(0),
+		httpserver.WithHTTP2StreamTimeout(200*time.Millisecond),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = server.StartTLS(ctx, "", "") }()
+
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(certPEM))
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: pool},
+		ForceAttemptHTTP2: true,
+	}}
+	url := "https://localhost:" + strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+
+	var resp *http.Response
+	require.Eventually(t, func() bool {
+		resp, err = client.Get(url + "/fast")
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	resp.Body.Close()
+	require.Equal(t, 2, resp.ProtoMajor)
+
+	// The slow stream times out on its own
+	slowErr := make(chan error, 1)
+	go func() {
+		resp, err := client.Get(url + "/slow")
+		if err == nil {
+			_, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		slowErr <- err
+	}()
+
+	// Other streams on the same connection are served meanwhile
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(url + "/fast")
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+		require.Equal(t, "HTTP/2.0", string(body))
+	}
+
+	select {
+	case err := <-slowCtxErr:
+		require.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("slow stream did not time out")
+	}
+	require.True(t, hasDeadline.Load())
+	require.Error(t, <-slowErr)
+}
+
+func TestShutdownNotify(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+	flush, other := make(chan struct{}), make(chan struct{})
+	server, err := httpserver.New(ln.Addr().String(), handler,
+		httpserver.WithListener(ln),
+		httpserver.WithShutdownNotify(flush),
+		httpserver.WithShutdownNotify(other),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() { stopped <- server.Start(ctx) }()
+
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+
+	// Both channels are notified at the start of the shutdown, while the request is still in flight
+	cancel()
+	for _, ch := range []chan struct{}{flush, other} {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			t.Fatal("shutdown was not notified")
+		}
+	}
+
+	close(release)
+	require.NoError(t, <-stopped)
+
+	// Notifying again must not panic on the closed channels
+	require.NoError(t, server.Close(context.Background()))
+}
+
+func TestKubernetesGracefulShutdown(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	addr := "http://" + ln.Addr().String()
+
+	log := &httpserver.MemoryLogger{}
+	mux := http.NewServeMux()
+	server, err := httpserver.New(ln.Addr().String(), mux,
+		httpserver.WithListener(ln),
+		httpserver.WithLogger(log),
+		httpserver.WithKubernetesGracefulShutdown(),
+		httpserver.WithShutdownDelay(300*time.Millisecond),
+	)
+	require.NoError(t, err)
+	mux.Handle("/readyz", server.ReadinessHandler())
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	require.Equal(t, 300*time.Millisecond, server.Config().ShutdownDelay)
+	require.Contains(t, server.Config().Features, "WithInflightTracking")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() { stopped <- server.Start(ctx) }()
+
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(addr + "/readyz")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, time.Second, 10*time.Millisecond)
+
+	// During the delay the readiness fails, while requests are still served
+	cancel()
+	require.Eventually(t, server.ShuttingDown, time.Second, 5*time.Millisecond)
+	resp, err := http.Get(addr + "/readyz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	resp, err = http.Get(addr + "/")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.True(t, resp.Close, "the response must close the keep-alive connection")
+
+	select {
+	case err := <-stopped:
+		require.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not stop after the delay")
+	}
+
+	require.Len(t, log.Find("waiting for load balancers to stop routing traffic"), 1)
+	done := log.Find("HTTP server shutdown complete")
+	require.Len(t, done, 1)
+	_, ok := done[0].Field("drained_connections")
+	require.True(t, ok)
+}
+
+func TestDrainConnectionClose(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	addr := "http://" + ln.Addr().String()
+
+	inflight, release := make(chan struct{}), make(chan struct{})
+	server, err := httpserver.New(ln.Addr().String(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(inflight)
+			<-release
+		}
+	}),
+		httpserver.WithListener(ln),
+		httpserver.WithShutdownDelay(time.Second),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() { stopped <- server.Start(ctx) }()
+
+	// Before the shutdown the connection is kept alive
+	var resp *http.Response
+	require.Eventually(t, func() bool {
+		resp, err = http.Get(addr)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	resp.Body.Close()
+	require.False(t, resp.Close)
+	require.Empty(t, resp.Header.Get("Connection"))
+
+	// A request in flight when the shutdown begins responds during the delay, so its connection is closed
+	slow := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get(addr + "/slow")
+		if err == nil {
+			resp.Body.Close()
+		}
+		slow <- resp
+	}()
+	<-inflight
+	cancel()
+	require.Eventually(t, server.ShuttingDown, time.Second, 5*time.Millisecond)
+	close(release)
+	resp = <-slow
+	require.NotNil(t, resp)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.True(t, resp.Close)
+
+	// During the shutdown delay new requests are served, but their connections are closed
+	resp, err = http.Get(addr)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.True(t, resp.Close)
+
+	require.NoError(t, <-stopped)
+}
+
+// flakyListener fails the given number of Accept calls with a temporary error before accepting connections.
+type flakyListener struct {
+	net.Listener
+	failures atomic.Int32
+	closed   atomic.Bool
+}
+
+var errFlakyAccept = errors.New("too many open files")
+
+func (l *flakyListener) Accept() (net.Conn, error) {
+	if l.closed.Load() {
+		return nil, net.ErrClosed
+	}
+	if l.failures.Add(-1) >= 0 {
+		return nil, errFlakyAccept
+	}
+	return l.Listener.Accept()
+}
+
+func (l *flakyListener) Close() error {
+	l.closed.Store(true)
+	return l.Listener.Close()
+}
+
+func TestAcceptErrorHandler(t *testing.T) {
+	newServer := func(t *testing.T, failures int32, recoverable bool) (*flakyListener, *httpserver.Server, *httpserver.MemoryLogger, *atomic.Int32) {
+		inner, err := net.Listen("tcp", "localhost:0")
+		require.NoError(t, err)
+		ln := &flakyListener{Listener: inner}
+		ln.failures.Store(failures)
+
+		var calls atomic.Int32
+		log := &httpserver.MemoryLogger{}
+		server, err := httpserver.New(ln.Addr().String(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+			httpserver.WithListener(ln),
+			httpserver.WithLogger(log),
+			httpserver.WithAcceptErrorHandler(func(err error) bool {
+				calls.Add(1)
+				return recoverable && errors.Is(err, errFlakyAccept)
+			}),
+		)
+		require.NoError(t, err)
+		return ln, server, log, &calls
+	}
+
+	t.Run("recoverable errors are retried", func(t *testing.T) {
+		ln, server, log, calls := newServer(t, 3, true)
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan error, 1)
+		go func() { stopped <- server.Start(ctx) }()
+
+		resp, err := http.Get("http://" + ln.Addr().String())
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.EqualValues(t, 3, calls.Load())
+		require.Len(t, log.Find("recoverable accept error, retrying"), 3)
+
+		cancel()
+		require.NoError(t, <-stopped)
+	})
+
+	t.Run("fatal error stops the server", func(t *testing.T) {
+		_, server, log, _ := newServer(t, 1, false)
+		err := server.Start(context.Background())
+		require.ErrorIs(t, err, errFlakyAccept)
+		require.ErrorIs(t, err, httpserver.ErrServerStart)
+		require.Len(t, log.Find("fatal accept error"), 1)
+	})
+
+	t.Run("shutdown interrupts the back-off", func(t *testing.T) {
+		_, server, _, calls := newServer(t, math.MaxInt32, true)
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan error, 1)
+		go func() { stopped <- server.Start(ctx) }()
+
+		// Wait until the back-off reaches several hundred milliseconds
+		require.Eventually(t, func() bool { return calls.Load() >= 8 }, 5*time.Second, 5*time.Millisecond)
+		cancel()
+		select {
+		case err := <-stopped:
+			require.NoError(t, err)
+		case <-time.After(200 * time.Millisecond):
+			t.Fatal("shutdown waited for the accept back-off")
+		}
+	})
+}
+
+func TestInflightTracking(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	log := &httpserver.MemoryLogger{}
+	server, err := httpserver.New(ln.Addr().String(), handler,
+		httpserver.WithListener(ln),
+		httpserver.WithLogger(log),
+		httpserver.WithInflightTracking(),
+	)
+	require.NoError(t, err)
+
+	go func() { _ = server.Start(context.Background()) }()
+	go func() {
+		req, _ := http.NewRequest(http.MethodPost, "http://"+ln.Addr().String()+"/reports/export", nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+
+	// The stuck request outlives the shutdown timeout and is reported
+	err = server.Stop(context.Background(), 100*time.Millisecond)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	summary := log.Find("requests did not complete within the shutdown timeout")
+	require.Len(t, summary, 1)
+	count, _ := summary[0].Field("count")
+	require.EqualValues(t, 1, count)
+
+	incomplete := log.Find("incomplete request")
+	require.Len(t, incomplete, 1)
+	method, _ := incomplete[0].Field("method")
+	require.Equal(t, http.MethodPost, method)
+	path, _ := incomplete[0].Field("path")
+	require.Equal(t, "/reports/export", path)
+	duration, _ := incomplete[0].Field("duration")
+	require.GreaterOrEqual(t, duration, 100*time.Millisecond)
+}
+
+func TestLogFields(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	log := &httpserver.MemoryLogger{}
+	server, err := httpserver.New(ln.Addr().String(), http.NotFoundHandler(),
+		httpserver.WithListener(ln),
+		httpserver.WithLogger(log),
+		httpserver.WithLogFields("service", "api"),
+		httpserver.WithLogFields("env", "production"),
+	)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- server.Start(ctx) }()
+	require.Eventually(t, func() bool { return log.Has("listening") }, time.Second, 5*time.Millisecond)
+	cancel()
+	require.NoError(t, <-stopped)
+
+	// Every lifecycle message carries the accumulated fields along with its own ones
+	for _, msg := range []string{"starting HTTP server", "listening", "stopping HTTP server", "HTTP server shutdown complete"} {
+		entries := log.Find(msg)
+		require.NotEmpty(t, entries, msg)
+		for _, e := range entries {
+			service, _ := e.Field("service")
+			require.Equal(t, "api", service, msg)
+			env, _ := e.Field("env")
+			require.Equal(t, "production", env, msg)
+		}
+	}
+	addr, ok := log.Find("listening")[0].Field("addr")
+	require.True(t, ok)
+	require.Equal(t, ln.Addr().String(), addr)
+}
+
+func TestErrorLog(t *testing.T) {
+	certPEM, keyPEM := generateTestCert(t)
+	tlsConfig, err := httpserver.TLSConfigFromPEM(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	// serve starts a TLS server and fails a handshake with a client which does not trust the certificate
+	// The error log is set with WithErrorLog only if setErrorLog is true
+	serve := func(t *testing.T, setErrorLog bool, errorLog *stdlog.Logger) *httpserver.MemoryLogger {
+		ln, err := net.Listen("tcp", "localhost:0")
+		require.NoError(t, err)
+		log := &httpserver.MemoryLogger{}
+		opts := optionsOf(httpserver.WithListener(ln), httpserver.WithLogger(log), httpserver.WithTLSConfig(tlsConfig))
+		if setErrorLog {
+			opts = append(opts, httpserver.WithErrorLog(errorLog))
+		}
+		server, err := httpserver.New(ln.Addr().String(), http.NotFoundHandler(), opts...)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan error, 1)
+		go func() { stopped <- server.StartTLS(ctx, "", "") }()
+		defer func() {
+			cancel()
+			require.NoError(t, <-stopped)
+		}()
+
+		_, err = http.Get("https://" + ln.Addr().String())
+		require.Error(t, err)
+		require.Eventually(t, func() bool { return server.Stats().TLSHandshakeFailures == 1 }, time.Second, 5*time.Millisecond)
+		return log
+	}
+
+	handshakeErrors := func(log *httpserver.MemoryLogger) []httpserver.LogEntry {
+		var result []httpserver.LogEntry
+		for _, e := range log.Entries() {
+			if strings.Contains(e.Message, "TLS handshake error") {
+				result = append(result, e)
+			}
+		}
+		return result
+	}
+
+	t.Run("net/http errors reach the logger", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "localhost:0")
+		require.NoError(t, err)
+		log := &httpserver.MemoryLogger{}
+		server, err := httpserver.New(ln.Addr().String(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}), httpserver.WithListener(ln), httpserver.WithLogger(log))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan error, 1)
+		go func() { stopped <- server.Start(ctx) }()
+		defer func() {
+			cancel()
+			require.NoError(t, <-stopped)
+		}()
+
+		require.Eventually(t, func() bool {
+			_, err := http.Get("http://" + ln.Addr().String())
+			return err != nil && !errors.Is(err, syscall.ECONNREFUSED)
+		}, time.Second, 10*time.Millisecond)
+
+		var entry httpserver.LogEntry
+		require.Eventually(t, func() bool {
+			for _, e := range log.Entries() {
+				if strings.HasPrefix(e.Message, "http: panic serving") {
+					entry = e
+					return true
+				}
+			}
+			return false
+		}, time.Second, 5*time.Millisecond)
+		require.Equal(t, httpserver.LevelError, entry.Level)
+		source, _ := entry.Field("source")
+		require.Equal(t, "net/http", source)
+	})
+
+	t.Run("TLS handshake errors are logged once", func(t *testing.T) {
+		log := serve(t, false, nil)
+		require.Eventually(t, func() bool { return log.Has("TLS handshake failed") }, time.Second, 5*time.Millisecond)
+		require.Len(t, log.Find("TLS handshake failed"), 1)
+		require.Empty(t, handshakeErrors(log))
+	})
+
+	t.Run("WithErrorLog(nil) opts out", func(t *testing.T) {
+		var std bytes.Buffer
+		stdlog.SetOutput(&std)
+		t.Cleanup(func() { stdlog.SetOutput(os.Stderr) })
+
+		require.Empty(t, handshakeErrors(serve(t, true, nil)))
+		require.Contains(t, std.String(), "TLS handshake error")
+	})
+
+	t.Run("WithErrorLog sets a custom logger", func(t *testing.T) {
+		var custom bytes.Buffer
+		require.Empty(t, handshakeErrors(serve(t, true, stdlog.New(&custom, "", 0))))
+		require.Contains(t, custom.String(), "TLS handshake error")
+	})
+}
+
+// optionsOf collects server options into a slice, since their type is not exported.
+func optionsOf[T any](opts ...T) []T {
+	return opts
+}
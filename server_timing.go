@@ -0,0 +1,112 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// serverTimingContextKey is the context key for the server timings of the request.
+type serverTimingContextKey struct{}
+
+// serverTimings collects the sub-timings recorded by the handler.
+// Handlers may record timings from several goroutines, so access is synchronized.
+type serverTimings struct {
+	mu      sync.Mutex
+	entries []serverTiming
+}
+
+// serverTiming is a single named duration of the Server-Timing header.
+type serverTiming struct {
+	name string
+	dur  time.Duration
+}
+
+// AddServerTiming records a named sub-timing of the request, e.g. the duration of a database query,
+// which ServerTimingMiddleware sends in the Server-Timing response header.
+// The name must be a valid HTTP token, e.g. "db" or "cache-lookup"; timings with invalid names are dropped.
+// It is safe for concurrent use and does nothing if the context does not come from a request
+// served through ServerTimingMiddleware.
+func AddServerTiming(ctx context.Context, name string, d time.Duration) {
+	t, ok := ctx.Value(serverTimingContextKey{}).(*serverTimings)
+	if !ok || !isToken(name) {
+		return
+	}
+	t.mu.Lock()
+	t.entries = append(t.entries, serverTiming{name: name, dur: d})
+	t.mu.Unlock()
+}
+
+// ServerTimingMiddleware sends the Server-Timing response header with the sub-timings recorded
+// by the handler via AddServerTiming, followed by the total handler duration, e.g.:
+//
+//	Server-Timing: db;dur=12.5, render;dur=3.25, total;dur=16.8
+//
+// Durations are in milliseconds. Browser developer tools show them in the network panel.
+// The header is assembled when the response header is written, so the total is the time to the first byte,
+// and timings recorded after the handler starts writing the body are not included.
+//
+// The header discloses the backend timings to every client; consider enabling the middleware
+// for internal or authenticated traffic only.
+func ServerTimingMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			timings := &serverTimings{}
+			setHeader := func(int) {
+				w.Header().Add("Server-Timing", timings.header(time.Since(start)))
+			}
+
+			rw := newResponseWriter(w)
+			rw.beforeWriteHeader = setHeader
+
+			next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), serverTimingContextKey{}, timings)))
+
+			// The handler returned without writing, the server writes the header after the handler returns
+			if !rw.wroteHeader {
+				setHeader(http.StatusOK)
+			}
+		})
+	}
+}
+
+// header formats the recorded timings and the total duration as the Server-Timing header value.
+func (t *serverTimings) header(total time.Duration) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var b strings.Builder
+	for _, e := range t.entries {
+		b.WriteString(e.name)
+		b.WriteString(";dur=")
+		b.WriteString(formatMilliseconds(e.dur))
+		b.WriteString(", ")
+	}
+	b.WriteString("total;dur=")
+	b.WriteString(formatMilliseconds(total))
+	return b.String()
+}
+
+// formatMilliseconds formats the duration in milliseconds with microsecond precision, e.g. "12.345".
+func formatMilliseconds(d time.Duration) string {
+	return strconv.FormatFloat(float64(d.Microseconds())/1000, 'f', -1, 64)
+}
+
+// isToken reports whether s is a valid HTTP token as defined by RFC 9110.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range []byte(s) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
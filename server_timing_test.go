@@ -0,0 +1,49 @@
+package httpserver_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerTimingMiddleware(t *testing.T) {
+	t.Run("header format", func(t *testing.T) {
+		handler := httpserver.ServerTimingMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			httpserver.AddServerTiming(r.Context(), "db", 12500*time.Microsecond)
+			httpserver.AddServerTiming(r.Context(), "cache-lookup", 250*time.Microsecond)
+			httpserver.AddServerTiming(r.Context(), "invalid name", time.Millisecond)
+			w.WriteHeader(http.StatusCreated)
+			httpserver.AddServerTiming(r.Context(), "late", time.Millisecond)
+		}))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		require.Equal(t, http.StatusCreated, rec.Code)
+		require.Regexp(t, regexp.MustCompile(`^db;dur=12\.5, cache-lookup;dur=0\.25, total;dur=\d+(\.\d+)?$`),
+			rec.Header().Get("Server-Timing"))
+	})
+
+	t.Run("handler does not write", func(t *testing.T) {
+		handler := httpserver.ServerTimingMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			httpserver.AddServerTiming(r.Context(), "db", time.Millisecond)
+		}))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		require.Regexp(t, regexp.MustCompile(`^db;dur=1, total;dur=\d+(\.\d+)?$`), rec.Header().Get("Server-Timing"))
+	})
+
+	t.Run("without middleware", func(t *testing.T) {
+		require.NotPanics(t, func() {
+			httpserver.AddServerTiming(context.Background(), "db", time.Millisecond)
+		})
+	})
+}
@@ -0,0 +1,128 @@
+package httpserver
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// defaultShutdownResponse is a minimal plain-text 503 response.
+var defaultShutdownResponse = &fixedResponse{
+	status:      http.StatusServiceUnavailable,
+	contentType: "text/plain; charset=utf-8",
+	body:        []byte(http.StatusText(http.StatusServiceUnavailable) + "\n"),
+}
+
+// shutdownResponderMiddleware rejects requests arriving while the server is shutting down
+// with the configured response and the Retry-After header, so that clients retry on another instance.
+// Requests which started before the shutdown are completed as usual.
+func (s *Server) shutdownResponderMiddleware(next http.Handler) http.Handler {
+	resp := s.shutdownResponse
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.ShuttingDown() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Retry-After", "1")
+		if resp == defaultShutdownResponse && s.errorRenderer != nil {
+			s.errorRenderer(w, r, resp.status, statusError(resp.status))
+			return
+		}
+		resp.write(w, r)
+	})
+}
+
+// shutdownSafeMiddleware replaces the responses of idempotent requests which have not started
+// when the server begins shutting down with the shutdown response,
+// instead of starting a write which may be cut off by the shutdown timeout.
+func (s *Server) shutdownSafeMiddleware(next http.Handler) http.Handler {
+	resp := s.shutdownResponse
+	if resp == nil {
+		resp = defaultShutdownResponse
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !idempotentMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		sw := &shutdownSafeWriter{ResponseWriter: w, server: s, resp: resp, req: r}
+		next.ServeHTTP(sw, r)
+	})
+}
+
+// shutdownSafeWriter sends the shutdown response in place of the handler response
+// if the server is shutting down when the handler starts writing.
+type shutdownSafeWriter struct {
+	http.ResponseWriter
+	server      *Server
+	resp        *fixedResponse
+	req         *http.Request
+	wroteHeader bool
+	aborted     bool
+}
+
+// WriteHeader writes the handler response header, or the shutdown response if the server is shutting down.
+func (w *shutdownSafeWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	// Informational responses do not start the final response
+	if status >= 100 && status < 200 && status != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	w.wroteHeader = true
+	if !w.server.ShuttingDown() {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	w.aborted = true
+	h := w.Header()
+	for _, key := range []string{"Cache-Control", "Content-Disposition", "Content-Encoding", "Content-Range", "ETag", "Last-Modified", "Set-Cookie"} {
+		h.Del(key)
+	}
+	h.Set("Cache-Control", "no-store")
+	h.Set("Retry-After", "1")
+	w.resp.write(w.ResponseWriter, w.req)
+}
+
+// Write writes the response body, or discards it and returns ErrResponseAborted if the response was replaced.
+func (w *shutdownSafeWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.aborted {
+		return 0, ErrResponseAborted
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends any buffered data to the client.
+func (w *shutdownSafeWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack lets the caller take over the connection.
+func (w *shutdownSafeWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the original http.ResponseWriter, so that http.ResponseController can reach it.
+func (w *shutdownSafeWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// idempotentMethod reports whether requests with the given method can be safely retried by the client.
+func idempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
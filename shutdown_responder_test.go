@@ -0,0 +1,210 @@
+package httpserver_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+// startShutdownTestServer starts the server on ln and waits until it accepts requests.
+// It returns a function which begins the shutdown and waits until the server reports it;
+// the server should use WithShutdownDelay, so that it keeps serving requests during the shutdown.
+func startShutdownTestServer(t *testing.T, ln net.Listener, server *httpserver.Server) (shutdown func()) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- server.Start(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, <-stopped)
+	})
+
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, time.Second, 10*time.Millisecond)
+
+	// The returned function may be called from another goroutine, so it polls without require
+	return func() {
+		cancel()
+		for !server.ShuttingDown() {
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+// doRequest sends a request to the address of ln and returns the response with its body.
+func doRequest(t *testing.T, ln net.Listener, method string) (*http.Response, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, "http://"+ln.Addr().String(), nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(body)
+}
+
+func TestShutdownResponder(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	t.Run("default response", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "localhost:0")
+		require.NoError(t, err)
+		server, err := httpserver.New(ln.Addr().String(), handler,
+			httpserver.WithListener(ln),
+			httpserver.WithShutdownDelay(500*time.Millisecond),
+			httpserver.WithShutdownResponder(),
+		)
+		require.NoError(t, err)
+		shutdown := startShutdownTestServer(t, ln, server)
+
+		resp, body := doRequest(t, ln, http.MethodGet)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "ok", body)
+
+		shutdown()
+		resp, _ = doRequest(t, ln, http.MethodGet)
+		require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		require.Equal(t, "1", resp.Header.Get("Retry-After"))
+		require.Equal(t, "text/plain; charset=utf-8", resp.Header.Get("Content-Type"))
+		require.True(t, resp.Close)
+	})
+
+	t.Run("custom response", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "localhost:0")
+		require.NoError(t, err)
+		server, err := httpserver.New(ln.Addr().String(), handler,
+			httpserver.WithListener(ln),
+			httpserver.WithShutdownDelay(500*time.Millisecond),
+			httpserver.WithShutdownResponse(http.StatusServiceUnavailable, "application/json", []byte(`{"error":"shutting down"}`)),
+		)
+		require.NoError(t, err)
+		shutdown := startShutdownTestServer(t, ln, server)
+
+		shutdown()
+		resp, body := doRequest(t, ln, http.MethodGet)
+		require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		require.Equal(t, "1", resp.Header.Get("Retry-After"))
+		require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		require.JSONEq(t, `{"error":"shutting down"}`, body)
+	})
+
+	t.Run("wraps user middlewares", func(t *testing.T) {
+		var called atomic.Bool
+		ln, err := net.Listen("tcp", "localhost:0")
+		require.NoError(t, err)
+		server, err := httpserver.New(ln.Addr().String(), handler,
+			httpserver.WithListener(ln),
+			httpserver.WithShutdownDelay(500*time.Millisecond),
+			httpserver.WithShutdownResponder(),
+			httpserver.WithMiddleware(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					called.Store(true)
+					next.ServeHTTP(w, r)
+				})
+			}),
+		)
+		require.NoError(t, err)
+		shutdown := startShutdownTestServer(t, ln, server)
+
+		shutdown()
+		resp, _ := doRequest(t, ln, http.MethodGet)
+		require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		require.False(t, called.Load())
+	})
+}
+
+func TestShutdownSafeResponses(t *testing.T) {
+	// newServer starts a server with the given handler, which gets a channel closed once the shutdown begins.
+	// The returned function waits for the handler to start, then begins the shutdown.
+	newServer := func(t *testing.T, handler func(w http.ResponseWriter, shuttingDown <-chan struct{}) error) (net.Listener, func(), <-chan error) {
+		started, shuttingDown := make(chan struct{}), make(chan struct{})
+		writeErr := make(chan error, 1)
+		ln, err := net.Listen("tcp", "localhost:0")
+		require.NoError(t, err)
+		server, err := httpserver.New(ln.Addr().String(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			writeErr <- handler(w, shuttingDown)
+		}),
+			httpserver.WithListener(ln),
+			httpserver.WithShutdownDelay(500*time.Millisecond),
+			httpserver.WithShutdownSafeResponses(),
+		)
+		require.NoError(t, err)
+		begin := startShutdownTestServer(t, ln, server)
+
+		shutdown := func() {
+			<-started
+			begin()
+			close(shuttingDown)
+		}
+		return ln, shutdown, writeErr
+	}
+
+	t.Run("response not started", func(t *testing.T) {
+		ln, shutdown, writeErr := newServer(t, func(w http.ResponseWriter, shuttingDown <-chan struct{}) error {
+			<-shuttingDown
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Content-Type", "application/json")
+			_, err := w.Write([]byte(`{"ok":true}`))
+			return err
+		})
+		go shutdown()
+
+		resp, body := doRequest(t, ln, http.MethodGet)
+		require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		require.Equal(t, "1", resp.Header.Get("Retry-After"))
+		require.Equal(t, "text/plain; charset=utf-8", resp.Header.Get("Content-Type"))
+		require.Empty(t, resp.Header.Get("ETag"))
+		require.Equal(t, "Service Unavailable\n", body)
+		require.ErrorIs(t, <-writeErr, httpserver.ErrResponseAborted)
+	})
+
+	t.Run("non-idempotent request", func(t *testing.T) {
+		ln, shutdown, writeErr := newServer(t, func(w http.ResponseWriter, shuttingDown <-chan struct{}) error {
+			<-shuttingDown
+			_, err := w.Write([]byte("created"))
+			return err
+		})
+		go shutdown()
+
+		resp, body := doRequest(t, ln, http.MethodPost)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "created", body)
+		require.NoError(t, <-writeErr)
+	})
+
+	t.Run("response already started", func(t *testing.T) {
+		ln, shutdown, writeErr := newServer(t, func(w http.ResponseWriter, shuttingDown <-chan struct{}) error {
+			if _, err := w.Write([]byte("part 1, ")); err != nil {
+				return err
+			}
+			<-shuttingDown
+			_, err := w.Write([]byte("part 2"))
+			return err
+		})
+		go shutdown()
+
+		resp, body := doRequest(t, ln, http.MethodGet)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "part 1, part 2", body)
+		require.NoError(t, <-writeErr)
+	})
+}
@@ -0,0 +1,48 @@
+package httpserver
+
+import (
+	"os"
+	"syscall"
+)
+
+// ShutdownAction is the behavior of the server on a shutdown signal, set per signal with WithSignalHandler.
+type ShutdownAction int
+
+const (
+	// GracefulDrain stops the server gracefully: the active requests are given the shutdown timeout to complete.
+	// It is the action for SIGINT and SIGTERM by default.
+	GracefulDrain ShutdownAction = iota
+	// ImmediateClose closes the server right away, aborting the active requests, e.g. for fast feedback on Ctrl+C.
+	ImmediateClose
+)
+
+// String returns the name of the action.
+func (a ShutdownAction) String() string {
+	switch a {
+	case GracefulDrain:
+		return "graceful_drain"
+	case ImmediateClose:
+		return "immediate_close"
+	default:
+		return "unknown"
+	}
+}
+
+// shutdownSignals returns the signals the server shuts down on: SIGINT, SIGTERM and the ones set with WithSignalHandler.
+func (s *Server) shutdownSignals() []os.Signal {
+	signals := []os.Signal{os.Interrupt, syscall.SIGTERM}
+	for sig := range s.signalActions {
+		if sig != os.Interrupt && sig != syscall.SIGTERM {
+			signals = append(signals, sig)
+		}
+	}
+	return signals
+}
+
+// signalAction returns the action for the given signal, GracefulDrain unless set otherwise with WithSignalHandler.
+func (s *Server) signalAction(sig os.Signal) ShutdownAction {
+	if action, ok := s.signalActions[sig]; ok {
+		return action
+	}
+	return GracefulDrain
+}
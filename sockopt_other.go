@@ -0,0 +1,10 @@
+//go:build !unix
+
+package httpserver
+
+import "net"
+
+// socketBufferSizes reports that the buffer sizes in effect cannot be read on this platform.
+func socketBufferSizes(*net.TCPConn) (read, write int, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,24 @@
+//go:build unix
+
+package httpserver
+
+import (
+	"net"
+	"syscall"
+)
+
+// socketBufferSizes returns the receive and send buffer sizes in effect for the connection.
+func socketBufferSizes(conn *net.TCPConn) (read, write int, ok bool) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return 0, 0, false
+	}
+	var readErr, writeErr error
+	if err := raw.Control(func(fd uintptr) {
+		read, readErr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_RCVBUF)
+		write, writeErr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_SNDBUF)
+	}); err != nil || readErr != nil || writeErr != nil {
+		return 0, 0, false
+	}
+	return read, write, true
+}
@@ -0,0 +1,92 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SSEWriter writes Server-Sent Events frames to the response and flushes them immediately.
+// It is not safe for concurrent use.
+type SSEWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// Event writes an event with the given name and data.
+// If the name is empty, the event is dispatched as a default "message" event.
+// Multi-line data is split into several data fields as required by the SSE format.
+func (s *SSEWriter) Event(event, data string) error {
+	var b strings.Builder
+	if event != "" {
+		b.WriteString("event: ")
+		b.WriteString(event)
+		b.WriteByte('\n')
+	}
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: ")
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	b.WriteByte('\n')
+
+	return s.write(b.String())
+}
+
+// Data writes a default "message" event with the given data.
+func (s *SSEWriter) Data(data string) error {
+	return s.Event("", data)
+}
+
+// Comment writes a comment line, which is ignored by clients.
+// It is useful as a keep-alive ping to prevent proxies from closing an idle stream.
+func (s *SSEWriter) Comment(text string) error {
+	return s.write(": " + text + "\n\n")
+}
+
+// Retry tells the client how long to wait before reconnecting after the stream is closed.
+func (s *SSEWriter) Retry(d time.Duration) error {
+	return s.write(fmt.Sprintf("retry: %d\n\n", d.Milliseconds()))
+}
+
+// write writes the frame and flushes it to the client.
+func (s *SSEWriter) write(frame string) error {
+	if _, err := s.w.Write([]byte(frame)); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// SSEHandler creates a new http.HandlerFunc for streaming Server-Sent Events.
+// It sets the event stream headers, disables the server write timeout for the request,
+// so that the default WriteTimeout does not kill a long-lived stream, and passes an SSEWriter to the handler.
+// The stream lasts until the handler returns; use r.Context() to detect client disconnects.
+//
+// Reverse proxies may buffer the response and delay the events.
+// The handler sets "X-Accel-Buffering: no" which disables buffering in nginx;
+// other proxies may need to be configured explicitly.
+func SSEHandler(fn func(w *SSEWriter, r *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+
+		// Rewind the write deadline set by the server WriteTimeout
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			renderError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		if err := rc.Flush(); err != nil {
+			// Headers are already sent, nothing else to do
+			return
+		}
+
+		fn(&SSEWriter{w: w, rc: rc}, r)
+	}
+}
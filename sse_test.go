@@ -0,0 +1,48 @@
+package httpserver_test
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSEHandler(t *testing.T) {
+	handler := httpserver.SSEHandler(func(w *httpserver.SSEWriter, r *http.Request) {
+		assert.NoError(t, w.Event("greeting", "hello\nworld"))
+		// Exceed the server write timeout between events
+		time.Sleep(300 * time.Millisecond)
+		assert.NoError(t, w.Data("bye"))
+	})
+
+	ts := httptest.NewUnstartedServer(handler)
+	ts.Config.WriteTimeout = 100 * time.Millisecond
+	ts.Start()
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	require.NoError(t, scanner.Err())
+	require.Equal(t, []string{
+		"event: greeting",
+		"data: hello",
+		"data: world",
+		"",
+		"data: bye",
+		"",
+	}, lines)
+}
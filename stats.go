@@ -0,0 +1,170 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// tlsFailureLogInterval is the minimum interval between TLS handshake failure log messages.
+const tlsFailureLogInterval = time.Second
+
+// ServerStats is a snapshot of the server statistics.
+type ServerStats struct {
+	// ActiveConnections is the number of connections handling a request.
+	ActiveConnections int `json:"active_connections"`
+	// IdleConnections is the number of keep-alive connections waiting for the next request.
+	IdleConnections int `json:"idle_connections"`
+	// NewConnections is the number of connections which have not sent a request yet.
+	NewConnections int `json:"new_connections"`
+	// TLSHandshakeFailures is the total number of TLS connections closed before the handshake completed,
+	// e.g. due to scanners, wrong SNI or clients not trusting the certificate.
+	TLSHandshakeFailures uint64 `json:"tls_handshake_failures"`
+	// InflightRequests is the number of requests being handled.
+	InflightRequests int64 `json:"inflight_requests"`
+	// Requests is the total number of handled requests by the response status class, from "1xx" to "5xx".
+	// It is only counted with WithRequestStats, and nil otherwise.
+	Requests map[string]uint64 `json:"requests,omitempty"`
+	// Uptime is the time since the server was started, or zero if it has not been started.
+	Uptime time.Duration `json:"-"`
+}
+
+// serverStats holds the counters of the server.
+type serverStats struct {
+	tlsHandshakeFailures atomic.Uint64
+	tlsFailureLoggedAt   atomic.Int64
+	tlsFailureSuppressed atomic.Uint64
+	startedAt            atomic.Int64
+	inflightRequests     atomic.Int64
+	// requests counts the handled requests by the status class, 1xx to 5xx
+	requests [5]atomic.Uint64
+}
+
+// StartedAt returns the time the server started listening on its address,
+// or the zero time if it has not been started.
+func (s *Server) StartedAt() time.Time {
+	startedAt := s.stats.startedAt.Load()
+	if startedAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, startedAt)
+}
+
+// Uptime returns the time since the server started listening on its address, or zero if it has not been started.
+func (s *Server) Uptime() time.Duration {
+	startedAt := s.StartedAt()
+	if startedAt.IsZero() {
+		return 0
+	}
+	return time.Since(startedAt)
+}
+
+// Stats returns a snapshot of the server statistics.
+func (s *Server) Stats() ServerStats {
+	stats := ServerStats{
+		TLSHandshakeFailures: s.stats.tlsHandshakeFailures.Load(),
+		InflightRequests:     s.stats.inflightRequests.Load(),
+	}
+	if s.requestStats {
+		stats.Requests = make(map[string]uint64, len(s.stats.requests))
+		for i := range s.stats.requests {
+			stats.Requests[strconv.Itoa(i+1)+"xx"] = s.stats.requests[i].Load()
+		}
+	}
+	stats.Uptime = s.Uptime()
+	for _, info := range s.conns.snapshot() {
+		switch info.state {
+		case http.StateActive:
+			stats.ActiveConnections++
+		case http.StateIdle:
+			stats.IdleConnections++
+		case http.StateNew:
+			stats.NewConnections++
+		}
+	}
+	return stats
+}
+
+// statsResponse is the JSON body of the stats handler.
+type statsResponse struct {
+	ServerStats
+	UptimeSeconds float64 `json:"uptime_seconds"`
+}
+
+// StatsHandler returns an http.HandlerFunc which responds with a snapshot of the server statistics as JSON:
+// connections, requests by status class (with WithRequestStats), in-flight requests and the uptime in seconds, e.g.:
+//
+//	{"active_connections":1,"idle_connections":0,"new_connections":0,"tls_handshake_failures":0,
+//	 "inflight_requests":1,"requests":{"1xx":0,"2xx":42,"3xx":0,"4xx":3,"5xx":0},"uptime_seconds":3600.5}
+//
+// It provides basic observability without any dependency on a metrics library.
+// The statistics may be sensitive, so mount the handler on an internal listener or behind authentication.
+func (s *Server) StatsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats := s.Stats()
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_ = json.NewEncoder(w).Encode(statsResponse{
+			ServerStats:   stats,
+			UptimeSeconds: stats.Uptime.Seconds(),
+		})
+	}
+}
+
+// statsMiddleware counts the in-flight requests and, with WithRequestStats, the handled requests
+// by the response status class. The response writer is wrapped only in the latter case.
+func (s *Server) statsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.stats.inflightRequests.Add(1)
+		defer s.stats.inflightRequests.Add(-1)
+		if !s.requestStats {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rw := newResponseWriter(w)
+		defer func() {
+			class := rw.Status()/100 - 1
+			if class >= 0 && class < len(s.stats.requests) {
+				s.stats.requests[class].Add(1)
+			}
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// observeTLSHandshake counts and logs TLS connections closed before the handshake completed.
+// Logging is rate-limited to avoid log floods; suppressed failures are reported with the next message.
+func (s *Server) observeTLSHandshake(c net.Conn, state http.ConnState) {
+	if state != http.StateClosed {
+		return
+	}
+	tc, ok := c.(*tls.Conn)
+	if !ok || tc.ConnectionState().HandshakeComplete {
+		return
+	}
+
+	s.stats.tlsHandshakeFailures.Add(1)
+
+	now := time.Now().UnixNano()
+	last := s.stats.tlsFailureLoggedAt.Load()
+	if now-last < int64(tlsFailureLogInterval) || !s.stats.tlsFailureLoggedAt.CompareAndSwap(last, now) {
+		s.stats.tlsFailureSuppressed.Add(1)
+		return
+	}
+
+	clientIP := c.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(clientIP); err == nil {
+		clientIP = host
+	}
+	s.log.ErrorContext(context.Background(), "TLS handshake failed",
+		"client_ip", clientIP,
+		"suppressed", s.stats.tlsFailureSuppressed.Swap(0),
+		"total", s.stats.tlsHandshakeFailures.Load(),
+	)
+}
@@ -0,0 +1,71 @@
+package httpserver
+
+import (
+	"net/http"
+	"strings"
+)
+
+// singletonHeaders are the request headers which must not be repeated.
+// Repeated values make requests ambiguous, since different servers and proxies pick different values.
+var singletonHeaders = []string{
+	"Content-Length",
+	"Content-Type",
+	"Authorization",
+	"Proxy-Authorization",
+	"Expect",
+	"If-Modified-Since",
+	"If-Unmodified-Since",
+	"If-Range",
+	"Range",
+}
+
+// StrictRequestMiddleware rejects requests with ambiguous or malformed framing
+// with 400 Bad Request, on top of the validation done by net/http.
+//
+// The following checks are performed:
+//   - the method is a token without lower-case letters, e.g. "M-SEARCH" is accepted, but "get" is rejected;
+//   - the request target contains no fragment ("#"), whitespace or ASCII control characters;
+//   - none of the singleton headers (Content-Length, Content-Type, Authorization,
+//     Proxy-Authorization, Expect, If-Modified-Since, If-Unmodified-Since, If-Range, Range) is repeated.
+//
+// net/http already rejects invalid header names and values, obsolete line folding,
+// conflicting Content-Length values, multiple Host headers and unsupported Transfer-Encoding values.
+// Bare LF line endings are accepted by net/http and cannot be detected at this level.
+// See also WithDisableGeneralOptionsHandler for the related server setting.
+func StrictRequestMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !validMethod(r.Method) || !validRequestTarget(r.RequestURI) || hasRepeatedSingletonHeader(r.Header) {
+				renderError(w, r, http.StatusBadRequest, statusError(http.StatusBadRequest))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// validMethod reports whether the method is a token (RFC 9110) without lower-case letters,
+// e.g. "GET" or "M-SEARCH", since methods are case-sensitive and "get" is most likely a mistake or an attack.
+func validMethod(method string) bool {
+	return isToken(method) && strings.ToUpper(method) == method
+}
+
+// validRequestTarget reports whether the raw request target has no fragment, whitespace or control characters.
+func validRequestTarget(target string) bool {
+	for i := 0; i < len(target); i++ {
+		if c := target[i]; c <= ' ' || c == 0x7f || c == '#' {
+			return false
+		}
+	}
+	return true
+}
+
+// hasRepeatedSingletonHeader reports whether any of the singleton headers has more than one value.
+func hasRepeatedSingletonHeader(h http.Header) bool {
+	for _, name := range singletonHeaders {
+		if len(h.Values(name)) > 1 {
+			return true
+		}
+	}
+	return false
+}
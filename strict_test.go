@@ -0,0 +1,86 @@
+package httpserver_test
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrictRequestMiddleware(t *testing.T) {
+	ts := httptest.NewServer(httpserver.StrictRequestMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+	defer ts.Close()
+
+	tests := []struct {
+		name     string
+		request  string
+		expected int
+	}{
+		{
+			name:     "valid request",
+			request:  "GET /path?q=1 HTTP/1.1\r\nHost: example.com\r\n\r\n",
+			expected: http.StatusOK,
+		},
+		{
+			name:     "token method with a hyphen",
+			request:  "M-SEARCH * HTTP/1.1\r\nHost: example.com\r\n\r\n",
+			expected: http.StatusOK,
+		},
+		{
+			name:     "token method with a digit",
+			request:  "REPORT2 /path HTTP/1.1\r\nHost: example.com\r\n\r\n",
+			expected: http.StatusOK,
+		},
+		{
+			name:     "lower-case method",
+			request:  "get /path HTTP/1.1\r\nHost: example.com\r\n\r\n",
+			expected: http.StatusBadRequest,
+		},
+		{
+			name:     "fragment in request target",
+			request:  "GET /path#fragment HTTP/1.1\r\nHost: example.com\r\n\r\n",
+			expected: http.StatusBadRequest,
+		},
+		{
+			name:     "control character in request target",
+			request:  "GET /pa\x01th HTTP/1.1\r\nHost: example.com\r\n\r\n",
+			expected: http.StatusBadRequest,
+		},
+		{
+			name:     "repeated Content-Type",
+			request:  "POST /path HTTP/1.1\r\nHost: example.com\r\nContent-Type: text/plain\r\nContent-Type: application/json\r\nContent-Length: 2\r\n\r\n{}",
+			expected: http.StatusBadRequest,
+		},
+		{
+			name:     "repeated Authorization",
+			request:  "GET /path HTTP/1.1\r\nHost: example.com\r\nAuthorization: Bearer a\r\nAuthorization: Bearer b\r\n\r\n",
+			expected: http.StatusBadRequest,
+		},
+		{
+			name:     "repeated Range",
+			request:  "GET /path HTTP/1.1\r\nHost: example.com\r\nRange: bytes=0-1\r\nRange: bytes=5-6\r\n\r\n",
+			expected: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+			require.NoError(t, err)
+			defer conn.Close()
+
+			_, err = fmt.Fprint(conn, tt.request)
+			require.NoError(t, err)
+
+			resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, tt.expected, resp.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package httpserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+)
+
+type tlsOption func(*tls.Config)
+
+// WithClientCAs enables mutual TLS: clients must present a certificate signed by one of the CAs in the pool.
+func WithClientCAs(pool *x509.CertPool) tlsOption {
+	return func(cfg *tls.Config) {
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+}
+
+// WithTLSMinVersion overrides the minimum TLS version, which is TLS 1.2 by default.
+func WithTLSMinVersion(version uint16) tlsOption {
+	return func(cfg *tls.Config) {
+		cfg.MinVersion = version
+	}
+}
+
+// TLSConfigFromPEM creates a TLS configuration with the certificate and the private key in PEM format,
+// e.g. embedded into the binary, to be used with WithTLSConfig or WithAdditionalListener.
+// It returns an error if the PEM data is invalid or the key does not match the certificate.
+//
+// The configuration uses secure defaults: TLS 1.2 as the minimum version
+// and only AEAD cipher suites with forward secrecy for TLS 1.2 (TLS 1.3 suites are not configurable).
+func TLSConfigFromPEM(certPEM, keyPEM []byte, opts ...tlsOption) (*tls.Config, error) {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidTLSCertificate, err)
+	}
+
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+	for _, o := range opts {
+		o(cfg)
+	}
+
+	return cfg, nil
+}
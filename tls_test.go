@@ -0,0 +1,68 @@
+package httpserver_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+// generateTestCert generates a self-signed certificate and key in PEM format.
+func generateTestCert(t *testing.T) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestTLSConfigFromPEM(t *testing.T) {
+	certPEM, keyPEM := generateTestCert(t)
+
+	cfg, err := httpserver.TLSConfigFromPEM(certPEM, keyPEM)
+	require.NoError(t, err)
+	require.Len(t, cfg.Certificates, 1)
+	require.EqualValues(t, tls.VersionTLS12, cfg.MinVersion)
+
+	pool := x509.NewCertPool()
+	cfg, err = httpserver.TLSConfigFromPEM(certPEM, keyPEM,
+		httpserver.WithClientCAs(pool),
+		httpserver.WithTLSMinVersion(tls.VersionTLS13),
+	)
+	require.NoError(t, err)
+	require.Same(t, pool, cfg.ClientCAs)
+	require.Equal(t, tls.RequireAndVerifyClientCert, cfg.ClientAuth)
+	require.EqualValues(t, tls.VersionTLS13, cfg.MinVersion)
+
+	// Mismatched key pair
+	_, otherKeyPEM := generateTestCert(t)
+	_, err = httpserver.TLSConfigFromPEM(certPEM, otherKeyPEM)
+	require.ErrorIs(t, err, httpserver.ErrInvalidTLSCertificate)
+
+	_, err = httpserver.TLSConfigFromPEM([]byte("invalid"), keyPEM)
+	require.ErrorIs(t, err, httpserver.ErrInvalidTLSCertificate)
+}
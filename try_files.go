@@ -0,0 +1,65 @@
+package httpserver
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// TryFiles creates a new http.HandlerFunc that serves the first existing file of the candidates, in order,
+// like the try_files directive of nginx, and passes the request to the fallback handler if none exists.
+// Candidates are file paths relative to the root, in which "$uri" is replaced with the request path.
+// A candidate ending with a slash, e.g. "$uri/", matches a directory and serves its "index.html".
+//
+// For example, a single-page application:
+//
+//	TryFiles(root, []string{"$uri", "$uri/", "/index.html"}, nil)
+//
+// or static assets in front of an application server:
+//
+//	TryFiles(root, []string{"$uri", "$uri.html"}, appHandler)
+//
+// Only GET and HEAD requests are served from files; other methods go straight to the fallback handler.
+// Dotfiles are never served, like with WithDenyDotfiles, so the candidate is skipped.
+// If the fallback handler is nil, 404 Not Found is returned. Files are served without caching headers,
+// like StaticHandler with a zero cache TTL. Use http.StripPrefix to mount the handler under a path prefix.
+func TryFiles(root http.FileSystem, candidates []string, fallback http.Handler) http.HandlerFunc {
+	if fallback == nil {
+		fallback = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			renderError(w, r, http.StatusNotFound, statusError(http.StatusNotFound))
+		})
+	}
+	cfg := newStaticConfig(0)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			fallback.ServeHTTP(w, r)
+			return
+		}
+
+		for _, c := range candidates {
+			name := strings.ReplaceAll(c, "$uri", r.URL.Path)
+			fsPath := path.Clean("/" + name)
+			if strings.HasSuffix(name, "/") {
+				fsPath = path.Join(fsPath, indexFile)
+			}
+			if hasDotSegment(fsPath) {
+				continue
+			}
+
+			file, info, err := openFile(root, fsPath)
+			if err != nil {
+				continue
+			}
+			defer file.Close()
+
+			if ct, ok := cfg.contentTypeFor(fsPath); ok {
+				w.Header().Set("Content-Type", ct)
+			}
+			serveFile(w, r, fsPath, file, info, cfg)
+			return
+		}
+
+		fallback.ServeHTTP(w, r)
+	}
+}
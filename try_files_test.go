@@ -0,0 +1,65 @@
+package httpserver_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTryFiles(t *testing.T) {
+	root := http.FS(fstest.MapFS{
+		"index.html":      {Data: []byte("<html>app</html>")},
+		"about.html":      {Data: []byte("<html>about</html>")},
+		"assets/app.js":   {Data: []byte("console.log('app')")},
+		"docs/index.html": {Data: []byte("<html>docs</html>")},
+		".env":            {Data: []byte("SECRET=1")},
+	})
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("app server"))
+	})
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		target  string
+		status  int
+		body    string
+	}{
+		// Single-page application
+		{name: "spa asset", handler: httpserver.TryFiles(root, []string{"$uri", "$uri/", "/index.html"}, nil), target: "/assets/app.js", status: http.StatusOK, body: "console.log('app')"},
+		{name: "spa directory", handler: httpserver.TryFiles(root, []string{"$uri", "$uri/", "/index.html"}, nil), target: "/docs", status: http.StatusOK, body: "<html>docs</html>"},
+		{name: "spa client route", handler: httpserver.TryFiles(root, []string{"$uri", "$uri/", "/index.html"}, nil), target: "/dashboard/settings?tab=1", status: http.StatusOK, body: "<html>app</html>"},
+		{name: "spa root", handler: httpserver.TryFiles(root, []string{"$uri", "$uri/", "/index.html"}, nil), target: "/", status: http.StatusOK, body: "<html>app</html>"},
+
+		// Static assets in front of an application server
+		{name: "asset", handler: httpserver.TryFiles(root, []string{"$uri", "$uri.html"}, app), target: "/assets/app.js", status: http.StatusOK, body: "console.log('app')"},
+		{name: "asset with extension", handler: httpserver.TryFiles(root, []string{"$uri", "$uri.html"}, app), target: "/about", status: http.StatusOK, body: "<html>about</html>"},
+		{name: "app fallback", handler: httpserver.TryFiles(root, []string{"$uri", "$uri.html"}, app), target: "/api/users", status: http.StatusOK, body: "app server"},
+		{name: "directory without candidate", handler: httpserver.TryFiles(root, []string{"$uri"}, app), target: "/docs", status: http.StatusOK, body: "app server"},
+		{name: "non-GET method", handler: httpserver.TryFiles(root, []string{"$uri"}, app), method: http.MethodPost, target: "/assets/app.js", status: http.StatusOK, body: "app server"},
+
+		{name: "path traversal", handler: httpserver.TryFiles(root, []string{"$uri"}, nil), target: "/assets/../../index.html", status: http.StatusOK, body: "<html>app</html>"},
+		{name: "dotfile", handler: httpserver.TryFiles(root, []string{"$uri"}, app), target: "/.env", status: http.StatusOK, body: "app server"},
+		{name: "no fallback", handler: httpserver.TryFiles(root, []string{"$uri"}, nil), target: "/missing.js", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			rec := httptest.NewRecorder()
+			tt.handler(rec, httptest.NewRequest(method, tt.target, nil))
+			require.Equal(t, tt.status, rec.Code)
+			if tt.body != "" {
+				require.Equal(t, tt.body, rec.Body.String())
+			}
+		})
+	}
+}
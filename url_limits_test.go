@@ -0,0 +1,51 @@
+package httpserver_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaxURLLengthMiddleware(t *testing.T) {
+	handler := httpserver.MaxURLLengthMiddleware(20)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		target   string
+		expected int
+	}{
+		{target: "/" + strings.Repeat("a", 19), expected: http.StatusOK},
+		{target: "/" + strings.Repeat("a", 20), expected: http.StatusRequestURITooLong},
+		{target: "/a?" + strings.Repeat("b", 17), expected: http.StatusOK},
+		{target: "/a?" + strings.Repeat("b", 18), expected: http.StatusRequestURITooLong},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		require.Equal(t, tt.expected, rec.Code, "len=%d", len(tt.target))
+	}
+}
+
+func TestMaxQueryParamsMiddleware(t *testing.T) {
+	handler := httpserver.MaxQueryParamsMiddleware(3)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		target   string
+		expected int
+	}{
+		{target: "/", expected: http.StatusOK},
+		{target: "/?a=1&b=2&c=3", expected: http.StatusOK},
+		{target: "/?a=1&&b=2&c=3&", expected: http.StatusOK},
+		{target: "/?a=1&a=2&a=3&a=4", expected: http.StatusRequestURITooLong},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		require.Equal(t, tt.expected, rec.Code, tt.target)
+	}
+}
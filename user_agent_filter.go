@@ -0,0 +1,67 @@
+package httpserver
+
+import (
+	"net/http"
+	"strings"
+)
+
+// UserAgentFilterMiddleware rejects requests whose User-Agent header matches any of the block patterns
+// with 403 Forbidden, as a lightweight first line of defense against scrapers and bots.
+// If rejectEmpty is set, requests without a User-Agent, or with a blank one, are rejected too.
+//
+// Patterns are matched case-insensitively. A pattern without wildcards matches a substring,
+// e.g. "python-requests"; a pattern with "*" wildcards, each matching any sequence of characters,
+// must match the whole value, e.g. "curl/*" or "*bot*". Keep in mind that the header is set by the client,
+// so a determined scraper can always spoof it.
+func UserAgentFilterMiddleware(block []string, rejectEmpty bool) func(http.Handler) http.Handler {
+	patterns := make([]string, 0, len(block))
+	for _, p := range block {
+		if p = strings.ToLower(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ua := strings.ToLower(strings.TrimSpace(r.UserAgent()))
+			if (ua == "" && rejectEmpty) || (ua != "" && matchesUserAgent(patterns, ua)) {
+				renderError(w, r, http.StatusForbidden, statusError(http.StatusForbidden))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// matchesUserAgent reports whether the lower-cased User-Agent matches any of the lower-cased patterns.
+func matchesUserAgent(patterns []string, ua string) bool {
+	for _, p := range patterns {
+		if !strings.Contains(p, "*") {
+			if strings.Contains(ua, p) {
+				return true
+			}
+			continue
+		}
+		if matchWildcard(p, ua) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWildcard reports whether s matches the whole pattern, where each "*" matches any sequence of characters.
+func matchWildcard(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return strings.HasSuffix(s, parts[len(parts)-1])
+}
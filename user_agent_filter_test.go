@@ -0,0 +1,50 @@
+package httpserver_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserAgentFilterMiddleware(t *testing.T) {
+	handler := httpserver.UserAgentFilterMiddleware(
+		[]string{"python-requests", "curl/*", "*spider*bot"},
+		true,
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name      string
+		userAgent string
+		expected  int
+	}{
+		{name: "browser", userAgent: "Mozilla/5.0 (X11; Linux x86_64) Firefox/120.0", expected: http.StatusOK},
+		{name: "empty", userAgent: "", expected: http.StatusForbidden},
+		{name: "blank", userAgent: "   ", expected: http.StatusForbidden},
+		{name: "blocked substring", userAgent: "python-requests/2.31.0", expected: http.StatusForbidden},
+		{name: "blocked substring case-insensitive", userAgent: "Scraper Python-Requests/2.31.0", expected: http.StatusForbidden},
+		{name: "glob prefix", userAgent: "curl/8.4.0", expected: http.StatusForbidden},
+		{name: "glob must match the whole value", userAgent: "libcurl/8.4.0", expected: http.StatusOK},
+		{name: "glob with several wildcards", userAgent: "Mozilla/5.0 (compatible) Spider-WebBot", expected: http.StatusForbidden},
+		{name: "glob suffix mismatch", userAgent: "SpiderBot crawler", expected: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("User-Agent", tt.userAgent)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			require.Equal(t, tt.expected, rec.Code)
+		})
+	}
+
+	t.Run("empty allowed", func(t *testing.T) {
+		handler := httpserver.UserAgentFilterMiddleware([]string{"curl"}, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+	})
+}
@@ -0,0 +1,68 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// VersionInfo represents the build metadata of the application.
+// It is returned as JSON by the VersionHandler.
+type VersionInfo struct {
+	Version   string `json:"version,omitempty"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	Dirty     bool   `json:"dirty"`
+	GoVersion string `json:"go_version"`
+}
+
+// ReadVersionInfo returns the build metadata embedded into the binary by the Go toolchain.
+// It populates the module version, the VCS revision, the commit time and the dirty state
+// if the binary was built with VCS stamping enabled (the default for `go build` in a repository).
+// Fields that are not available are left empty.
+func ReadVersionInfo() VersionInfo {
+	info := VersionInfo{GoVersion: runtime.Version()}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	if bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+		info.Version = bi.Main.Version
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Commit = s.Value
+		case "vcs.time":
+			info.BuildTime = s.Value
+		case "vcs.modified":
+			info.Dirty = s.Value == "true"
+		}
+	}
+
+	return info
+}
+
+// VersionHandler returns an http.HandlerFunc that responds with the given build metadata as JSON.
+// The Go runtime version is filled in automatically if it is not set.
+// It is intended to be mounted at a path like "/version".
+func VersionHandler(info VersionInfo) http.HandlerFunc {
+	if info.GoVersion == "" {
+		info.GoVersion = runtime.Version()
+	}
+
+	body, err := json.Marshal(info)
+	if err != nil {
+		// VersionInfo contains only marshalable fields, so this should never happen.
+		panic(err)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache")
+		_, _ = w.Write(body)
+	}
+}
@@ -0,0 +1,57 @@
+package httpserver_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionHandler(t *testing.T) {
+	serve := func(info httpserver.VersionInfo) (*httptest.ResponseRecorder, map[string]interface{}) {
+		rec := httptest.NewRecorder()
+		httpserver.VersionHandler(info).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		return rec, body
+	}
+
+	t.Run("build metadata", func(t *testing.T) {
+		rec, body := serve(httpserver.VersionInfo{
+			Version:   "v1.2.3",
+			Commit:    "abc123",
+			BuildTime: "2024-01-01T00:00:00Z",
+			Dirty:     true,
+			GoVersion: "go1.21.0",
+		})
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		require.Equal(t, "no-cache", rec.Header().Get("Cache-Control"))
+		require.Equal(t, map[string]interface{}{
+			"version":    "v1.2.3",
+			"commit":     "abc123",
+			"build_time": "2024-01-01T00:00:00Z",
+			"dirty":      true,
+			"go_version": "go1.21.0",
+		}, body)
+	})
+
+	t.Run("Go version default", func(t *testing.T) {
+		_, body := serve(httpserver.VersionInfo{Version: "v1.2.3"})
+		require.Equal(t, runtime.Version(), body["go_version"])
+		require.Equal(t, false, body["dirty"])
+		require.NotContains(t, body, "commit")
+		require.NotContains(t, body, "build_time")
+	})
+}
+
+func TestReadVersionInfo(t *testing.T) {
+	info := httpserver.ReadVersionInfo()
+	require.Equal(t, runtime.Version(), info.GoVersion)
+	// Test binaries are built without a module version
+	require.Empty(t, info.Version)
+}
@@ -0,0 +1,98 @@
+package httpserver
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// wellKnownPrefix is the path prefix of the well-known URIs defined by RFC 8615.
+const wellKnownPrefix = "/.well-known/"
+
+// wellKnownContentTypes are the content types of the well-known resources which cannot be derived from the extension.
+var wellKnownContentTypes = map[string]string{
+	"security.txt":               "text/plain; charset=utf-8",
+	"apple-app-site-association": "application/json",
+}
+
+// WellKnownHandler returns an http.HandlerFunc serving the given well-known resources (RFC 8615),
+// keyed by their name relative to "/.well-known/", e.g.:
+//
+//	mux.Handle("/.well-known/", httpserver.WellKnownHandler(map[string]string{
+//		"security.txt":         "Contact: mailto:security@example.com\nExpires: 2030-01-01T00:00:00Z\n",
+//		"acme-challenge/TOKEN": "TOKEN.THUMBPRINT",
+//	}))
+//
+// The handler must be mounted at "/.well-known/". Unknown resources get 404 Not Found,
+// methods other than GET and HEAD get 405 Method Not Allowed.
+// The content type is derived from the name: security.txt is served as plain text,
+// apple-app-site-association as JSON, other names by their extension, and names without one as plain text.
+func WellKnownHandler(resources map[string]string) http.HandlerFunc {
+	byName := make(map[string]string, len(resources))
+	for name, content := range resources {
+		byName[strings.Trim(strings.TrimPrefix(name, wellKnownPrefix), "/")] = content
+	}
+	return serveWellKnown(func(name string) (io.ReadSeeker, time.Time, bool) {
+		content, ok := byName[name]
+		return strings.NewReader(content), time.Time{}, ok
+	})
+}
+
+// WellKnownFSHandler is like WellKnownHandler, but serves the well-known resources from the file system,
+// e.g. os.DirFS("well-known") or an embedded directory, with the file paths relative to its root.
+// The files are read as a whole on every request, so they are expected to be small.
+func WellKnownFSHandler(fsys fs.FS) http.HandlerFunc {
+	return serveWellKnown(func(name string) (io.ReadSeeker, time.Time, bool) {
+		info, err := fs.Stat(fsys, name)
+		if err != nil || info.IsDir() {
+			return nil, time.Time{}, false
+		}
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, time.Time{}, false
+		}
+		return bytes.NewReader(content), info.ModTime(), true
+	})
+}
+
+// serveWellKnown returns an http.HandlerFunc serving the well-known resources returned by the lookup function.
+func serveWellKnown(lookup func(name string) (io.ReadSeeker, time.Time, bool)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			renderError(w, r, http.StatusMethodNotAllowed, statusError(http.StatusMethodNotAllowed))
+			return
+		}
+
+		rest, ok := strings.CutPrefix(r.URL.Path, wellKnownPrefix)
+		name := strings.TrimPrefix(path.Clean("/"+rest), "/")
+		if !ok || !fs.ValidPath(name) || name == "." {
+			renderError(w, r, http.StatusNotFound, statusError(http.StatusNotFound))
+			return
+		}
+		content, modTime, ok := lookup(name)
+		if !ok {
+			renderError(w, r, http.StatusNotFound, statusError(http.StatusNotFound))
+			return
+		}
+
+		w.Header().Set("Content-Type", wellKnownContentType(name))
+		http.ServeContent(w, r, name, modTime, content)
+	}
+}
+
+// wellKnownContentType returns the content type of the well-known resource with the given name.
+func wellKnownContentType(name string) string {
+	if ct, ok := wellKnownContentTypes[path.Base(name)]; ok {
+		return ct
+	}
+	if ct := mime.TypeByExtension(path.Ext(name)); ct != "" {
+		return ct
+	}
+	return "text/plain; charset=utf-8"
+}
@@ -0,0 +1,67 @@
+package httpserver_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dmitrymomot/httpserver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWellKnownHandler(t *testing.T) {
+	securityTxt := "Contact: mailto:security@example.com\n"
+
+	handlers := map[string]http.HandlerFunc{
+		"map": httpserver.WellKnownHandler(map[string]string{
+			"security.txt":               securityTxt,
+			"acme-challenge/token":       "token.thumbprint",
+			"apple-app-site-association": `{"applinks":{}}`,
+			"assetlinks.json":            `[]`,
+		}),
+		"fs": httpserver.WellKnownFSHandler(fstest.MapFS{
+			"security.txt":               {Data: []byte(securityTxt)},
+			"acme-challenge/token":       {Data: []byte("token.thumbprint")},
+			"apple-app-site-association": {Data: []byte(`{"applinks":{}}`)},
+			"assetlinks.json":            {Data: []byte(`[]`)},
+		}),
+	}
+
+	for name, handler := range handlers {
+		handler := handler
+		t.Run(name, func(t *testing.T) {
+			tests := []struct {
+				path        string
+				status      int
+				contentType string
+				body        string
+			}{
+				{path: "/.well-known/security.txt", status: http.StatusOK, contentType: "text/plain; charset=utf-8", body: securityTxt},
+				{path: "/.well-known/acme-challenge/token", status: http.StatusOK, contentType: "text/plain; charset=utf-8", body: "token.thumbprint"},
+				{path: "/.well-known/apple-app-site-association", status: http.StatusOK, contentType: "application/json", body: `{"applinks":{}}`},
+				{path: "/.well-known/assetlinks.json", status: http.StatusOK, contentType: "application/json", body: `[]`},
+				{path: "/.well-known/missing", status: http.StatusNotFound},
+				{path: "/.well-known/", status: http.StatusNotFound},
+				{path: "/.well-known/acme-challenge", status: http.StatusNotFound},
+				{path: "/security.txt", status: http.StatusNotFound},
+			}
+
+			for _, tt := range tests {
+				rec := httptest.NewRecorder()
+				handler(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+				require.Equal(t, tt.status, rec.Code, tt.path)
+				if tt.status == http.StatusOK {
+					require.Equal(t, tt.contentType, rec.Header().Get("Content-Type"), tt.path)
+					require.Equal(t, tt.body, rec.Body.String(), tt.path)
+				}
+			}
+
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest(http.MethodPost, "/.well-known/security.txt", nil))
+			require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+			require.Equal(t, "GET, HEAD", rec.Header().Get("Allow"))
+		})
+	}
+}